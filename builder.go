@@ -0,0 +1,90 @@
+package sipuri
+
+// Builder constructs a URI incrementally, as an alternative to passing all the
+// options to [New] up front. The zero value is ready to use and builds a SIP
+// URI.
+//
+//	uri := new(sipuri.Builder).User("alice").Host("atlanta.com").SetParam("transport", "tcp").Build()
+type Builder struct {
+	proto   Protocol
+	user    string
+	pass    string
+	host    string
+	params  KeyValuePairs
+	headers KeyValuePairs
+}
+
+// Scheme sets the protocol of the URI.
+func (b *Builder) Scheme(proto Protocol) *Builder {
+	b.proto = proto
+
+	return b
+}
+
+// User sets the user portion of the URI.
+func (b *Builder) User(user string) *Builder {
+	b.user = user
+
+	return b
+}
+
+// Password sets the password portion of the user-info, see [WithPassword].
+func (b *Builder) Password(pass string) *Builder {
+	b.pass = pass
+
+	return b
+}
+
+// Host sets the host portion of the URI, including any port.
+func (b *Builder) Host(host string) *Builder {
+	b.host = host
+
+	return b
+}
+
+// SetParam sets the param key to value, replacing any existing values.
+func (b *Builder) SetParam(key, value string) *Builder {
+	if b.params == nil {
+		b.params = make(KeyValuePairs, 1)
+	}
+
+	b.params.Set(key, value)
+
+	return b
+}
+
+// AddHeader adds the value to the header key, appending to any existing values.
+func (b *Builder) AddHeader(key, value string) *Builder {
+	if b.headers == nil {
+		b.headers = make(KeyValuePairs, 1)
+	}
+
+	b.headers.Add(key, value)
+
+	return b
+}
+
+// Build returns the URI, equal to that of the equivalent [New] call. The
+// builder may continue to be used without affecting the returned URI.
+func (b *Builder) Build() URI {
+	opts := []uriOption{WithPassword(b.pass)}
+
+	if b.proto == SIPS {
+		opts = append(opts, Secure())
+	}
+
+	if b.params != nil {
+		opts = append(opts, WithParams(b.params.Clone()))
+	}
+
+	if b.headers != nil {
+		opts = append(opts, WithHeaders(b.headers.Clone()))
+	}
+
+	return New(b.user, b.host, opts...)
+}
+
+// BuildString returns the string representation of the built URI.
+func (b *Builder) BuildString() string {
+	return b.Build().String()
+}
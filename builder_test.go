@@ -0,0 +1,37 @@
+package sipuri_test
+
+import (
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	builder := new(sipuri.Builder).
+		Scheme(sipuri.SIPS).
+		User("alice").
+		Password("secret").
+		Host("atlanta.com:5061").
+		SetParam("transport", "udp").
+		SetParam("transport", "tcp").
+		AddHeader("subject", "project x").
+		AddHeader("subject", "again")
+
+	expected := sipuri.New("alice", "atlanta.com:5061",
+		sipuri.Secure(),
+		sipuri.WithPassword("secret"),
+		sipuri.WithParams(sipuri.KeyValuePairs{"transport": {"tcp"}}),
+		sipuri.WithHeaders(sipuri.KeyValuePairs{"subject": {"project x", "again"}}),
+	)
+
+	uri := builder.Build()
+	equalF(t, expected, uri, "equivalent to new")
+	equalF(t, "sips:alice:secret@atlanta.com:5061;transport=tcp?subject=project%20x&subject=again", builder.BuildString(), "string")
+
+	builder.SetParam("lr", "").Scheme(sipuri.SIP)
+	equalF(t, "sips:alice:secret@atlanta.com:5061;transport=tcp?subject=project%20x&subject=again", uri.String(), "built uri unaffected")
+
+	equalF(t, sipuri.New("bob", "biloxi.com"), new(sipuri.Builder).User("bob").Host("biloxi.com").Build(), "no params or headers")
+}
@@ -0,0 +1,408 @@
+package sipuri
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// normalizer holds the options used by [URI.Normalize].
+type normalizer struct {
+	trailingDot bool
+}
+
+type normalizeOption func(n *normalizer)
+
+// WithoutTrailingDot strips the single trailing dot of an absolute hostname,
+// so atlanta.com. normalizes to atlanta.com. IP literals are left untouched.
+func WithoutTrailingDot() normalizeOption {
+	return func(n *normalizer) {
+		n.trailingDot = true
+	}
+}
+
+// Normalize returns a canonical copy of the URI for caching and
+// deduplication. The host is lower cased, an explicit port equal to the
+// default of the scheme & transport is removed, the transport param value is
+// lower cased and the method param value upper cased. The user and password
+// are case-sensitive so left intact.
+func (sipURI URI) Normalize(opts ...normalizeOption) URI {
+	var conf normalizer
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	normal := sipURI.Clone()
+	normal.host = strings.ToLower(sipURI.host)
+	normal.raw = ""
+	normal.rawHost = ""
+	_ = normal.splitHost()
+
+	if host, port, err := normal.SplitHostPort(); err == nil && conf.trailingDot && isAbsoluteHostname(host) {
+		normal.host = joinHostPort(host[:len(host)-1], port)
+		_ = normal.splitHost()
+	}
+
+	if host, port, err := normal.SplitHostPort(); err == nil && port != "" {
+		withoutPort := normal
+		withoutPort.host = joinHostPort(host, "")
+		_ = withoutPort.splitHost()
+
+		if withoutPort.Port() == port {
+			normal = withoutPort
+		}
+	}
+
+	if transport := normal.Params().Get("transport"); transport != strings.ToLower(transport) {
+		normal.params = storeWith(normal.Params(), "transport", strings.ToLower(transport), true)
+	}
+
+	if method := normal.Params().Get("method"); method != strings.ToUpper(method) {
+		normal.params = storeWith(normal.Params(), "method", strings.ToUpper(method), true)
+	}
+
+	return normal
+}
+
+// isAbsoluteHostname reports if the host, without port, is a hostname ending
+// in a single dot.
+func isAbsoluteHostname(host string) bool {
+	return len(host) > 1 && host[0] != '[' && strings.HasSuffix(host, ".") && !strings.HasSuffix(host, "..")
+}
+
+// joinHostPort combines the host and port, bracketing IPv6 addresses. An empty
+// port is omitted.
+func joinHostPort(host, port string) string {
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+
+	if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		return "[" + host + "]"
+	}
+
+	return host
+}
+
+// Equal reports whether the two URIs are equivalent following the comparison
+// rules of §19.1.4.
+//
+//   - The scheme, host and parameter names are compared case-insensitively,
+//     where as the user and password are case-sensitive.
+//   - The values of the transport, user, method, maddr and ttl params are
+//     compared case-insensitively, all other param values are case-sensitive.
+//   - Escaped characters are compared in their decoded form.
+//   - A port, user, ttl, method, maddr or transport present in only one of the
+//     URIs never matches, even if it contains the default value.
+//   - All other parameters appearing in only one URI are ignored.
+//   - Headers are never ignored, they must be present in both URIs and match.
+//
+// Equal is not transitive, sip:carol@chicago.com;security=on and
+// sip:carol@chicago.com;security=off both equal sip:carol@chicago.com but
+// not each other.
+func (sipURI URI) Equal(other URI) bool {
+	return sipURI.EqualFunc(other, CompareOptions{})
+}
+
+// CompareOptions relaxes the comparison of [URI.EqualFunc]. The zero value
+// follows §19.1.4 exactly, as [URI.Equal].
+type CompareOptions struct {
+	// CaseInsensitiveUser compares the user ignoring case, as some deployments
+	// treat usernames case-insensitively despite §19.1.4.
+	CaseInsensitiveUser bool
+	// IgnorePassword skips comparing the passwords.
+	IgnorePassword bool
+	// IgnoreParams skips comparing the params, even those which must
+	// otherwise appear in both URIs such as transport.
+	IgnoreParams bool
+	// IgnoreHeaders skips comparing the headers.
+	IgnoreHeaders bool
+}
+
+// EqualFunc reports whether the two URIs are equivalent like [URI.Equal] but
+// with the comparison relaxed by the given options.
+func (sipURI URI) EqualFunc(other URI, opts CompareOptions) bool {
+	if sipURI.proto != other.proto {
+		return false
+	}
+
+	if opts.CaseInsensitiveUser && !strings.EqualFold(sipURI.user, other.user) ||
+		!opts.CaseInsensitiveUser && sipURI.user != other.user {
+		return false
+	}
+
+	if !opts.IgnorePassword && sipURI.pass != other.pass {
+		return false
+	}
+
+	if !equalHost(sipURI, other) {
+		return false
+	}
+
+	if !opts.IgnoreParams && !equalParams(pairsOf(sipURI.Params()), pairsOf(other.Params())) {
+		return false
+	}
+
+	return opts.IgnoreHeaders || equalHeaders(pairsOf(sipURI.Headers()), pairsOf(other.Headers()))
+}
+
+// SameEndpoint reports whether the two URIs address the same endpoint,
+// ignoring all params and headers unlike [URI.Equal]. The scheme, user and
+// password must match, the host is compared case-insensitively and the ports
+// after applying the defaults of [URI.Port].
+func (sipURI URI) SameEndpoint(other URI) bool {
+	if sipURI.proto != other.proto || sipURI.user != other.user || sipURI.pass != other.pass {
+		return false
+	}
+
+	return strings.EqualFold(sipURI.HostName(), other.HostName()) && sipURI.Port() == other.Port()
+}
+
+// Diff describes each component stopping the two URIs being [URI.Equal], for
+// debugging why they differ. The result is empty exactly when they are equal.
+//
+// The params and headers are described by their lower cased names. The
+// passwords are not included, only that they differ.
+func (sipURI URI) Diff(other URI) []string {
+	var diff []string
+
+	if sipURI.proto != other.proto {
+		diff = append(diff, fmt.Sprintf("scheme: %s != %s", sipURI.Scheme(), other.Scheme()))
+	}
+
+	if sipURI.user != other.user {
+		diff = append(diff, fmt.Sprintf("user: %q != %q", sipURI.user, other.user))
+	}
+
+	if sipURI.pass != other.pass {
+		diff = append(diff, "password differs")
+	}
+
+	host, port, err := sipURI.SplitHostPort()
+	otherHost, otherPort, otherErr := other.SplitHostPort()
+
+	if err != nil || otherErr != nil {
+		host, otherHost, port, otherPort = sipURI.host, other.host, "", ""
+	}
+
+	if !strings.EqualFold(host, otherHost) {
+		diff = append(diff, fmt.Sprintf("host: %q != %q", host, otherHost))
+	}
+
+	if port != otherPort {
+		diff = append(diff, fmt.Sprintf("port: %q != %q", port, otherPort))
+	}
+
+	diff = diffPairs(diff, "param", pairsOf(sipURI.Params()), pairsOf(other.Params()), func(key string, vals, otherVals []string) bool {
+		if vals == nil || otherVals == nil {
+			return !isRequiredParam(key)
+		}
+
+		return equalParamValues(key, vals, otherVals)
+	})
+
+	return diffPairs(diff, "header", pairsOf(sipURI.Headers()), pairsOf(other.Headers()), func(_ string, vals, otherVals []string) bool {
+		return vals != nil && otherVals != nil && equalValues(vals, otherVals, func(a, b string) bool { return a == b })
+	})
+}
+
+// diffPairs appends a description of each key whose values are not equal,
+// which are nil when the key is missing, in sorted order.
+func diffPairs(diff []string, kind string, pairs, other KeyValuePairs, equal func(key string, vals, otherVals []string) bool) []string {
+	pairs, other = foldKeys(pairs), foldKeys(other)
+
+	union := pairs.Clone()
+	union.MergeSet(other)
+
+	for _, key := range union.SortedKeys() {
+		vals, otherVals := pairs[key], other[key]
+
+		if !equal(key, vals, otherVals) {
+			diff = append(diff, fmt.Sprintf("%s %s: %s != %s", kind, key, describeValues(vals), describeValues(otherVals)))
+		}
+	}
+
+	return diff
+}
+
+// describeValues quotes the values or describes them as missing when nil.
+func describeValues(vals []string) string {
+	if vals == nil {
+		return "missing"
+	}
+
+	return fmt.Sprintf("%q", vals)
+}
+
+// isRequiredParam reports if the param is one of the [requiredParams].
+func isRequiredParam(key string) bool {
+	for _, param := range requiredParams {
+		if key == param {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HashKey returns a string for use as a map key to deduplicate URIs, which is
+// the same for any two URIs that are [URI.Equal].
+//
+// As Equal is not transitive the key leaves out the params that Equal ignores
+// when present in only one URI, so URIs differing only by those params share
+// a key even when not equal.
+func (sipURI URI) HashKey() string {
+	key := URI{proto: sipURI.proto, user: sipURI.user, pass: sipURI.pass}
+
+	if host, port, err := sipURI.SplitHostPort(); err == nil {
+		key.host = joinHostPort(strings.ToLower(host), port)
+	} else {
+		key.host = strings.ToLower(sipURI.host)
+	}
+
+	params := foldKeys(pairsOf(sipURI.Params()))
+	required := make(KeyValuePairs, len(requiredParams))
+
+	for _, name := range requiredParams {
+		vals, ok := params[name]
+		if !ok {
+			continue
+		}
+
+		// Each of the required params is also case-insensitive.
+		lower := make([]string, len(vals))
+		for i, val := range vals {
+			lower[i] = strings.ToLower(val)
+		}
+
+		required[name] = lower
+	}
+
+	key.params = required
+	key.headers = foldKeys(pairsOf(sipURI.Headers()))
+
+	return key.String()
+}
+
+// equalHost compares the host case-insensitively and the port exactly.
+func equalHost(uri, other URI) bool {
+	host, port, err := uri.SplitHostPort()
+	otherHost, otherPort, otherErr := other.SplitHostPort()
+
+	if err != nil || otherErr != nil {
+		return strings.EqualFold(uri.host, other.host)
+	}
+
+	return strings.EqualFold(host, otherHost) && port == otherPort
+}
+
+// §19.1.4 "A user, ttl, or method uri-parameter appearing in only one URI
+// never matches, even if it contains the default value. A URI that includes
+// an maddr parameter will not match a URI that contains no maddr parameter."
+//
+// transport also falls under "A URI omitting any component with a default
+// value will not match a URI explicitly containing that component with its
+// default value".
+//
+//nolint:gochecknoglobals
+var requiredParams = [...]string{"transport", "user", "ttl", "method", "maddr"}
+
+// equalParams compares the params as per §19.1.4. Names are case-insensitive
+// as are the values of the [caseInsensitiveParams].
+func equalParams(params, other KeyValuePairs) bool {
+	params, other = foldKeys(params), foldKeys(other)
+
+	for _, key := range requiredParams {
+		_, has := params[key]
+		_, otherHas := other[key]
+
+		if has != otherHas {
+			return false
+		}
+	}
+
+	for key, vals := range params {
+		otherVals, ok := other[key]
+		if !ok {
+			continue // parameters appearing in only one URI are ignored
+		}
+
+		if !equalParamValues(key, vals, otherVals) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// caseInsensitiveParams are the params whose values are tokens or hosts,
+// which are compared case-insensitively.
+//
+//nolint:gochecknoglobals
+var caseInsensitiveParams = [...]string{"transport", "user", "method", "maddr", "ttl"}
+
+// equalParamValues compares the values of the param key, which must be lower
+// case, ignoring case only for the [caseInsensitiveParams].
+func equalParamValues(key string, vals, other []string) bool {
+	for _, param := range caseInsensitiveParams {
+		if key == param {
+			return equalValues(vals, other, strings.EqualFold)
+		}
+	}
+
+	return equalValues(vals, other, func(a, b string) bool { return a == b })
+}
+
+// equalHeaders compares the headers, which must all be present in both. Names
+// are case-insensitive whilst values must match exactly.
+func equalHeaders(headers, other KeyValuePairs) bool {
+	headers, other = foldKeys(headers), foldKeys(other)
+
+	if len(headers) != len(other) {
+		return false
+	}
+
+	for key, vals := range headers {
+		otherVals, ok := other[key]
+		if !ok || !equalValues(vals, otherVals, func(a, b string) bool { return a == b }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalValues(vals, other []string, equal func(string, string) bool) bool {
+	if len(vals) != len(other) {
+		return false
+	}
+
+	for i := range vals {
+		if !equal(vals[i], other[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// foldKeys returns a copy of the map with all keys lower cased. Values
+// of keys which fold to the same name are combined in sorted key order, so
+// the result doesn't depend on map iteration, and flags are treated as having
+// an empty value.
+func foldKeys(pairs KeyValuePairs) KeyValuePairs {
+	folded := make(KeyValuePairs, len(pairs))
+
+	for _, key := range pairs.SortedKeys() {
+		vals := pairs[key]
+		if len(vals) == 0 {
+			vals = []string{""}
+		}
+
+		lower := strings.ToLower(key)
+		folded[lower] = append(folded[lower], vals...)
+	}
+
+	return folded
+}
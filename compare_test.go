@@ -0,0 +1,361 @@
+package sipuri_test
+
+import (
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		other string
+		equal bool
+		msg   string
+	}
+
+	// From https://www.rfc-editor.org/rfc/rfc3261#section-19.1.4
+	tests := []test{
+		{"sip:%61lice@atlanta.com;transport=TCP", "sip:alice@AtLanTa.CoM;Transport=tcp", true, "RFC equivalent #1"},
+		{"sip:carol@chicago.com", "sip:carol@chicago.com;newparam=5", true, "RFC equivalent #2"},
+		{"sip:carol@chicago.com", "sip:carol@chicago.com;security=on", true, "RFC equivalent #2"},
+		{"sip:carol@chicago.com;newparam=5", "sip:carol@chicago.com;security=on", true, "RFC equivalent #2"},
+		{
+			"sip:biloxi.com;transport=tcp;method=REGISTER?to=sip:bob%40biloxi.com",
+			"sip:biloxi.com;method=REGISTER;transport=tcp?to=sip:bob%40biloxi.com",
+			true, "RFC equivalent #3",
+		},
+		{
+			"sip:alice@atlanta.com?subject=project%20x&priority=urgent",
+			"sip:alice@atlanta.com?priority=urgent&subject=project%20x",
+			true, "RFC equivalent #4",
+		},
+
+		{"SIP:ALICE@AtLanTa.CoM;Transport=udp", "sip:alice@AtLanTa.CoM;Transport=UDP", false, "RFC different usernames"},
+		{"sip:bob@biloxi.com", "sip:bob@biloxi.com:5060", false, "RFC can resolve to different ports"},
+		{"sip:bob@biloxi.com", "sip:bob@biloxi.com;transport=udp", false, "RFC can resolve to different transports"},
+		{"sip:bob@biloxi.com", "sip:bob@biloxi.com:6000;transport=tcp", false, "RFC can resolve to different port and transports"},
+		{"sip:carol@chicago.com", "sip:carol@chicago.com?Subject=next%20meeting", false, "RFC different header component"},
+		{"sip:bob@phone21.boxesbybob.com", "sip:bob@192.0.2.4", false, "RFC host name vs address"},
+		{"sip:carol@chicago.com;security=on", "sip:carol@chicago.com;security=off", false, "RFC intransitive"},
+
+		{"sip:alice@atlanta.com", "sips:alice@atlanta.com", false, "different scheme"},
+		{"sip:alice:secret@atlanta.com", "sip:alice:Secret@atlanta.com", false, "different password"},
+		{"sip:atlanta.com", "sip:alice@atlanta.com", false, "omitted user"},
+		{"sip:alice@atlanta.com;maddr=239.255.255.1", "sip:alice@atlanta.com", false, "maddr in one"},
+		{"sip:alice@atlanta.com;ttl=15", "sip:alice@atlanta.com", false, "ttl in one"},
+		{"sip:alice@atlanta.com;user=phone", "sip:alice@atlanta.com", false, "user in one"},
+		{"sip:alice@atlanta.com;method=INVITE", "sip:alice@atlanta.com", false, "method in one"},
+		{"sip:alice@atlanta.com?Subject=x", "sip:alice@atlanta.com?subject=x", true, "header name case"},
+		{"sip:alice@atlanta.com?subject=X", "sip:alice@atlanta.com?subject=x", false, "header value case"},
+		{"sip:alice@[::1]:5060", "sip:alice@[::1]:5060", true, "ipv6 host"},
+		{"sip:alice@atlanta.com;transport=TCP", "sip:alice@atlanta.com;transport=tcp", true, "transport value case"},
+		{"sip:alice@atlanta.com;method=invite", "sip:alice@atlanta.com;method=INVITE", true, "method value case"},
+		{"sip:alice@atlanta.com;maddr=Example.com", "sip:alice@atlanta.com;maddr=example.COM", true, "maddr value case"},
+		{"sip:alice@atlanta.com;x=ABC", "sip:alice@atlanta.com;x=abc", false, "other param value case"},
+		{"sip:alice@atlanta.com;X=abc", "sip:alice@atlanta.com;x=abc", true, "other param name case"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf(`failed to parse SIP URI %q, %v error`, test.uri, err)
+			}
+
+			other, err := parse(test.other)
+			if err != nil {
+				t.Fatalf(`failed to parse SIP URI %q, %v error`, test.other, err)
+			}
+
+			equalF(t, test.equal, uri.Equal(*other), "comparing %s", test.msg)
+			equalF(t, test.equal, other.Equal(*uri), "comparing reversed %s", test.msg)
+			equalF(t, test.equal, len(uri.Diff(*other)) == 0, "diff agrees in %s", test.msg)
+			equalF(t, test.equal, uri.EqualFunc(*other, sipuri.CompareOptions{}), "default options agree in %s", test.msg)
+
+			if test.equal {
+				equalF(t, uri.HashKey(), other.HashKey(), "hash key in %s", test.msg)
+			}
+		}
+	}
+}
+
+func TestEqualFunc(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		other string
+		opts  sipuri.CompareOptions
+		equal bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", "sip:ALICE@atlanta.com", sipuri.CompareOptions{}, false, "user case by default"},
+		{"sip:alice@atlanta.com", "sip:ALICE@atlanta.com", sipuri.CompareOptions{CaseInsensitiveUser: true}, true, "case-insensitive user"},
+		{"sip:alice@atlanta.com", "sip:bob@atlanta.com", sipuri.CompareOptions{CaseInsensitiveUser: true}, false, "different users"},
+
+		{"sip:alice:a@atlanta.com", "sip:alice:b@atlanta.com", sipuri.CompareOptions{}, false, "password by default"},
+		{"sip:alice:a@atlanta.com", "sip:alice@atlanta.com", sipuri.CompareOptions{IgnorePassword: true}, true, "ignore password"},
+
+		{"sip:alice@atlanta.com;transport=tcp", "sip:alice@atlanta.com", sipuri.CompareOptions{}, false, "params by default"},
+		{"sip:alice@atlanta.com;transport=tcp", "sip:alice@atlanta.com;transport=udp", sipuri.CompareOptions{IgnoreParams: true}, true, "ignore params"},
+		{"sip:alice@atlanta.com;transport=tcp", "sip:alice@atlanta.com", sipuri.CompareOptions{IgnoreParams: true}, true, "ignore required params"},
+		{"sip:alice@atlanta.com;transport=tcp", "sip:alice@atlanta.com", sipuri.CompareOptions{IgnoreHeaders: true}, false, "ignore headers keeps params"},
+
+		{"sip:alice@atlanta.com?subject=x", "sip:alice@atlanta.com", sipuri.CompareOptions{}, false, "headers by default"},
+		{"sip:alice@atlanta.com?subject=x", "sip:alice@atlanta.com?subject=y", sipuri.CompareOptions{IgnoreHeaders: true}, true, "ignore headers"},
+		{"sip:alice@atlanta.com?subject=x", "sip:alice@atlanta.com", sipuri.CompareOptions{IgnoreParams: true}, false, "ignore params keeps headers"},
+
+		{"sip:alice@atlanta.com", "sips:alice@atlanta.com", sipuri.CompareOptions{CaseInsensitiveUser: true, IgnorePassword: true, IgnoreParams: true, IgnoreHeaders: true}, false, "scheme always compared"},
+		{"sip:alice@atlanta.com", "sip:alice@biloxi.com", sipuri.CompareOptions{CaseInsensitiveUser: true, IgnorePassword: true, IgnoreParams: true, IgnoreHeaders: true}, false, "host always compared"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			other, err := parse(test.other)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.equal, uri.EqualFunc(*other, test.opts), "comparing %s", test.msg)
+			equalF(t, test.equal, other.EqualFunc(*uri, test.opts), "comparing reversed %s", test.msg)
+		}
+	}
+}
+
+func TestEqualCaseVariantKeys(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"sip:a@b?Subject=a&subject=b",
+		"sip:a@b;Foo=a;foo=b",
+	} {
+		uri, err := sipuri.Parse(input)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		// Keys differing only by case are merged, which mustn't depend on
+		// map iteration order.
+		for i := 0; i < 100; i++ {
+			equalF(t, true, uri.Equal(*uri), "%q equals itself", input)
+		}
+	}
+}
+
+func TestSameEndpoint(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		other string
+		same  bool
+		equal bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:alice@h;transport=tcp", "sip:alice@h;transport=udp", true, false, "different transports"},
+		{"sip:alice@atlanta.com", "sip:alice@atlanta.com?subject=x", true, false, "headers ignored"},
+		{"sip:alice@AtLanTa.CoM", "SIP:alice@atlanta.com:5060", true, false, "host case and default port"},
+		{"sips:alice@[::1]", "sips:alice@[::1]:5061", true, false, "ipv6 default port"},
+		{"sip:alice@atlanta.com", "sips:alice@atlanta.com", false, false, "different schemes"},
+		{"sip:alice@atlanta.com", "sip:ALICE@atlanta.com", false, false, "user case-sensitive"},
+		{"sip:alice:a@atlanta.com", "sip:alice:b@atlanta.com", false, false, "different passwords"},
+		{"sip:alice@atlanta.com", "sip:alice@atlanta.com:5070", false, false, "different ports"},
+		{"sip:alice@atlanta.com;transport=tls", "sip:alice@atlanta.com", false, false, "different default ports"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			other, err := parse(test.other)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.same, uri.SameEndpoint(*other), "same endpoint mismatch in %s", test.msg)
+			equalF(t, test.same, other.SameEndpoint(*uri), "symmetric same endpoint mismatch in %s", test.msg)
+			equalF(t, test.equal, uri.Equal(*other), "equal mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri    string
+		normal string
+		msg    string
+	}
+
+	tests := []test{
+		{"sip:Alice:PaSS@AtLanTa.CoM", "sip:Alice:PaSS@atlanta.com", "host lower cased"},
+		{"sip:alice@atlanta.com:5060", "sip:alice@atlanta.com", "default sip port"},
+		{"sips:alice@atlanta.com:5061", "sips:alice@atlanta.com", "default sips port"},
+		{"sip:alice@atlanta.com:5061", "sip:alice@atlanta.com:5061", "non-default port"},
+		{"sip:alice@atlanta.com:5061;transport=TLS", "sip:alice@atlanta.com;transport=tls", "default tls port"},
+		{"sip:alice@atlanta.com:5060;transport=TLS", "sip:alice@atlanta.com:5060;transport=tls", "non-default tls port"},
+		{"sip:alice@[2001:DB8::2:1]:5060", "sip:alice@[2001:db8::2:1]", "ipv6 default port"},
+		{"sip:alice@[2001:DB8::2:1]:5080", "sip:alice@[2001:db8::2:1]:5080", "ipv6 non-default port"},
+		{"sip:alice@[::1]", "sip:alice@[::1]", "ipv6 without port"},
+		{"sip:alice@atlanta.com;Transport=TCP", "sip:alice@atlanta.com;Transport=TCP", "transport param name is matched exactly"},
+		{"sip:registrar.biloxi.com;method=register", "sip:registrar.biloxi.com;method=REGISTER", "method upper cased"},
+		{"sip:registrar.biloxi.com;method=REGISTER", "sip:registrar.biloxi.com;method=REGISTER", "method already upper case"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.normal, uri.Normalize().String(), "normalized string %s", test.msg)
+			equalF(t, test.uri, uri.String(), "original modified %s", test.msg)
+		}
+	}
+}
+
+func TestNormalizeWithoutTrailingDot(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri    string
+		normal string
+		msg    string
+	}
+
+	tests := []test{
+		{"sip:alice@Atlanta.com.", "sip:alice@atlanta.com", "absolute hostname"},
+		{"sip:alice@atlanta.com.:5070", "sip:alice@atlanta.com:5070", "absolute hostname with port"},
+		{"sip:alice@atlanta.com.:5060", "sip:alice@atlanta.com", "absolute hostname with default port"},
+		{"sip:alice@atlanta.com", "sip:alice@atlanta.com", "relative hostname"},
+		{"sip:alice@a.", "sip:alice@a", "single character label"},
+		{"sip:alice@atlanta.com..", "sip:alice@atlanta.com..", "double trailing dot"},
+		{"sip:alice@.", "sip:alice@.", "root only"},
+		{"sip:alice@[2001:db8::2:1]", "sip:alice@[2001:db8::2:1]", "ipv6"},
+		{"sip:alice@192.0.2.4", "sip:alice@192.0.2.4", "ipv4"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.normal, uri.Normalize(sipuri.WithoutTrailingDot()).String(), "normalized string %s", test.msg)
+		}
+	}
+
+	absolute, err := sipuri.Parse("sip:alice@atlanta.com.")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	relative, err := sipuri.Parse("sip:alice@atlanta.com")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, false, absolute.Equal(*relative), "absolute and relative differ")
+	equalF(t, false, absolute.Normalize().Equal(relative.Normalize()), "differ by default")
+	equalF(t, true, absolute.Normalize(sipuri.WithoutTrailingDot()).Equal(relative.Normalize(sipuri.WithoutTrailingDot())), "equal without trailing dot")
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice:secret@atlanta.com:5060;transport=tcp;lr?subject=x")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, []string(nil), uri.Diff(*uri), "identical")
+
+		other, err := parse("sips:bob:other@AtLanTa.CoM:5061;Transport=UDP;maddr=239.255.255.1?Subject=y&priority=urgent")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, []string{
+			"scheme: sip != sips",
+			`user: "alice" != "bob"`,
+			"password differs",
+			`port: "5060" != "5061"`,
+			`param maddr: missing != ["239.255.255.1"]`,
+			`param transport: ["tcp"] != ["UDP"]`,
+			`header priority: missing != ["urgent"]`,
+			`header subject: ["x"] != ["y"]`,
+		}, uri.Diff(*other), "all components")
+
+		other, err = parse("sip:alice:secret@atlanta.com:5060;transport=udp;lr?subject=x")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, []string{`param transport: ["tcp"] != ["udp"]`}, uri.Diff(*other), "transport param")
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	t.Parallel()
+
+	seen := make(map[string]int)
+
+	for _, input := range []string{
+		"sip:%61lice@atlanta.com;transport=TCP?Subject=x",
+		"sip:alice@AtLanTa.CoM;Transport=tcp;lr?subject=x",
+		"sip:alice@atlanta.com;transport=udp?subject=x",
+		"sip:alice@atlanta.com:5060;transport=tcp?subject=x",
+		"sips:alice@atlanta.com;transport=tcp?subject=x",
+		"sip:alice@atlanta.com;transport=tcp?subject=X",
+	} {
+		uri, err := sipuri.Parse(input)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		seen[uri.HashKey()]++
+	}
+
+	equalF(t, 5, len(seen), "duplicates removed")
+
+	uri, err := sipuri.Parse("sip:%61lice@AtLanTa.CoM;Transport=TCP;lr?Subject=x")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, 2, seen[uri.HashKey()], "equal uris share a key")
+}
+
+func TestHashKeyCaseVariantKeys(t *testing.T) {
+	t.Parallel()
+
+	uri, err := sipuri.Parse("sip:a@b;Transport=TCP;transport=udp?Subject=a&subject=b")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	key := uri.HashKey()
+
+	for i := 0; i < 100; i++ {
+		equalF(t, key, uri.HashKey(), "stable key")
+	}
+}
@@ -9,6 +9,10 @@ import (
 // ErrInvalidScheme is returned when a string that does not start sip: or sips: is given.
 var ErrInvalidScheme = errors.New("sip: scheme invalid")
 
+// ErrUnbracketedIPv6 is returned when the host looks like an IPv6 address not
+// enclosed in brackets, which §19.1.2 requires.
+var ErrUnbracketedIPv6 = errors.New("sip: IPv6 address must be enclosed in brackets")
+
 // MalformCause indicates what part of the URI failed to be parsed.
 type MalformCause uint8
 
@@ -22,6 +26,9 @@ const (
 	MalformedHost
 	MalformedParams
 	MalformedHeaders
+	DeprecatedPassword
+	TooLong
+	TransportSchemeMismatch
 )
 
 // String returns a description of the cause.
@@ -41,6 +48,12 @@ func (c MalformCause) String() string {
 		return "malformed params"
 	case MalformedHeaders:
 		return "malformed headers"
+	case DeprecatedPassword:
+		return "deprecated password"
+	case TooLong:
+		return "too long"
+	case TransportSchemeMismatch:
+		return "transport scheme mismatch"
 	default:
 		panic("unreachable")
 	}
@@ -50,6 +63,11 @@ func (c MalformCause) String() string {
 type MalformedURIError struct {
 	Cause MalformCause
 	Err   error
+	// Offset is the index into the input where parsing failed, such as the
+	// position of a malformed escape or where the missing host should be.
+	// Zero when unknown, the scheme always precedes any malformed part. It is
+	// not included in the text of Error.
+	Offset int
 }
 
 // Error returns a string representation of the error.
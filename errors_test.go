@@ -44,6 +44,7 @@ func TestMalformCause(t *testing.T) {
 	tests := []sipuri.MalformCause{
 		sipuri.Unspecified, sipuri.MissingUser, sipuri.MissingHost,
 		sipuri.MalformedUser, sipuri.MalformedParams, sipuri.MalformedHeaders,
+		sipuri.DeprecatedPassword, sipuri.TooLong, sipuri.TransportSchemeMismatch,
 	}
 
 	for _, test := range tests {
@@ -1,6 +1,8 @@
 package sipuri
 
 import (
+	"fmt"
+	"io"
 	"sort"
 	"strings"
 )
@@ -15,7 +17,9 @@ type encoding int
 
 const (
 	encodeHost encoding = 1 + iota
-	encodeUserPassword
+	encodeHostLenient
+	encodeUser
+	encodePassword
 	encodeQueryComponent
 )
 
@@ -32,10 +36,16 @@ func (mode encoding) shouldEscape(char byte) bool {
 		return false
 	}
 
-	if mode == encodeHost {
-		// §3.2.2 Host allows:
+	switch mode { //nolint:exhaustive
+	case encodeHost:
+		// RFC 3261 §25.1 host only allows the alphanums, '-' and '.' of a
+		// hostname, with ':' and the brackets of an IPv6 reference.
+		return char != '-' && char != '.' && char != ':' && char != '[' && char != ']'
+	case encodeHostLenient:
+		// §3.2.2 reg-name also allows the sub-delims. '<', '>' and '"' are
+		// not allowed as they would delimit the URI in a SIP message.
 		switch char {
-		case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '[', ']', '<', '>', '"':
+		case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '[', ']':
 			return false
 		}
 	}
@@ -48,12 +58,13 @@ func (mode encoding) shouldEscape(char byte) bool {
 		// Different sections of the URI allow a few of
 		// the reserved characters to appear unescaped.
 		switch mode { //nolint:exhaustive
-		case encodeUserPassword: // §3.2.1
-			// The RFC allows ';', ':', '&', '=', '+', '$', and ',' in
-			// userinfo, so we must escape only '@', '/', and '?'.
-			// The parsing of userinfo treats ':' as special so we must escape
-			// that too.
-			return char == '@' || char == '/' || char == '?' || char == ':'
+		case encodeUser: // RFC 3261 §25.1 user-unreserved
+			// The user allows '&', '=', '+', '$', ',', ';', '?' and '/', so
+			// only the '@' and ':' delimiting the userinfo are escaped.
+			return char == '@' || char == ':'
+		case encodePassword: // RFC 3261 §25.1 password
+			// The password only allows '&', '=', '+', '$' and ','.
+			return char != '&' && char != '=' && char != '+' && char != '$' && char != ','
 		case encodeQueryComponent: // §3.4
 			// The RFC reserves (so we must escape) everything.
 			return true
@@ -84,70 +95,199 @@ func escape(input string, mode encoding) string {
 	required := len(input) + 2*hexCount //nolint:gomnd
 	result := make([]byte, required)
 
-	escapeInto(input, 0, result)
+	escapeInto(input, 0, result, mode)
 
 	return string(result)
 }
 
+// Component identifies the part of a URI a string is escaped for by [Escape].
+type Component uint8
+
+// The components of a URI with differing sets of characters allowed unescaped.
+const (
+	// User is the user, which allows the reserved characters except '@' and
+	// ':'. The '+' of a global number, as in
+	// sip:+1-212-555-1212@gateway.com;user=phone, is kept literally.
+	User Component = iota
+	// Password is the password, which allows fewer of the reserved characters
+	// than the user, only '&', '=', '+', '$' and ','.
+	Password
+	// Host allows only the characters of a hostname along with ':' and the
+	// brackets of an IPv6 reference.
+	Host
+	// Param is a key or value of the params, which escapes all the reserved
+	// characters including '+' as described by [EncodeURLValues].
+	Param
+	// Header is a key or value of the headers, escaped as for [Param].
+	Header
+)
+
+// encoding returns the escaping mode used for the component, falling back to
+// the most restrictive mode for an unknown component.
+func (c Component) encoding() encoding {
+	switch c {
+	case User:
+		return encodeUser
+	case Password:
+		return encodePassword
+	case Host:
+		return encodeHost
+	default:
+		return encodeQueryComponent
+	}
+}
+
+// Escape percent-encodes the input for use as the given component of a URI,
+// as is done by [URI.String]. An unknown component escapes all the reserved
+// characters, as for [Param].
+func Escape(input string, component Component) string {
+	return escape(input, component.encoding())
+}
+
 // DecodeURLValues decodes the input into the url.Values type, spliting
 // key-value pairs on the separator.
+//
+// A key without an '=' is stored as a flag, mapped to an empty slice, unless
+// the key also appears with a value. Empty pairs, such as of a trailing
+// separator, are skipped.
 func DecodeURLValues(input string, separator string) (KeyValuePairs, error) {
+	result, _, err := decodeURLValues(input, separator)
+
+	return result, err
+}
+
+// decodeURLValues is [DecodeURLValues] also returning the index of the
+// malformed escape on error.
+func decodeURLValues(input string, separator string) (KeyValuePairs, int, error) {
+	return decodeURLValuesInto(nil, input, separator)
+}
+
+// decodeURLValuesInto is [decodeURLValues] adding the pairs to the given
+// empty map, or a new map when nil.
+func decodeURLValuesInto(result KeyValuePairs, input string, separator string) (KeyValuePairs, int, error) {
 	pairs := strings.Split(input, separator)
 
-	// len(pairs) is the maximum number of unique keys possible. This may
-	// end up using more memory but in our use case duplicate keys are
-	// unlikely making this a worthy optimisation.
-	result := make(KeyValuePairs, len(pairs))
+	if result == nil {
+		// len(pairs) is the maximum number of unique keys possible. This may
+		// end up using more memory but in our use case duplicate keys are
+		// unlikely making this a worthy optimisation.
+		result = make(KeyValuePairs, len(pairs))
+	}
+
+	offset := 0
 
 	for _, pair := range pairs {
-		key, value, _ := strings.Cut(pair, "=")
+		// An empty pair, as in a;;b or a trailing separator, has nothing to
+		// store.
+		if pair == "" {
+			offset += len(separator)
 
-		key, err := Unescape(key)
-		if err != nil {
-			return nil, err
+			continue
 		}
 
-		value, err = Unescape(value)
+		rawKey, rawValue, hasValue := strings.Cut(pair, "=")
+
+		key, pos, err := unescape(rawKey)
 		if err != nil {
-			return nil, err
+			return nil, offset + pos, err
+		}
+
+		if !hasValue {
+			if _, ok := result[key]; !ok {
+				result[key] = []string{}
+			}
+		} else {
+			value, pos, err := unescape(rawValue)
+			if err != nil {
+				return nil, offset + len(rawKey) + 1 + pos, err
+			}
+
+			result[key] = append(result[key], value)
 		}
 
-		result[key] = append(result[key], value)
+		offset += len(pair) + len(separator)
 	}
 
-	return result, nil
+	return result, 0, nil
+}
+
+// DecodeURLValuesLenient decodes the input like [DecodeURLValues] but skips
+// each pair with a malformed escape rather than failing, so the well-formed
+// pairs of a malformed input can be recovered. The [EscapeError] of each
+// skipped pair is wrapped with the index of the pair.
+func DecodeURLValuesLenient(input string, separator string) (KeyValuePairs, []error) {
+	var errs []error
+
+	pairs := strings.Split(input, separator)
+	result := make(KeyValuePairs, len(pairs))
+
+	for i, pair := range pairs {
+		// A pair with a malformed escape leaves the map unchanged.
+		if _, _, err := decodeURLValuesInto(result, pair, separator); err != nil {
+			errs = append(errs, fmt.Errorf("sip: pair %d: %w", i, err))
+		}
+	}
+
+	return result, errs
 }
 
 // EncodeURLValues encodes all non-alpha numeric byte values;
-// notibly it encodes spaces as "%20" rather than a '+'.
+// notibly it encodes spaces as "%20" rather than a '+'. Keys without any
+// values are written without an '='.
+//
+// A '+' is escaped as "%2B", so a global number such as +1-212-555-1212 in a
+// param value is written as %2B1-212-555-1212. Both forms decode back to the
+// '+' as, unlike [url.ParseQuery], a '+' is never decoded as a space.
 //
 // Based on [url.Values.Encode()] but encodes spaces differently.
 // It is also slightly more efficient at 10% faster, with around 35% less
 // bytes written & over half the allocations per operation.
+func EncodeURLValues(input map[string][]string) string {
+	return encodeURLValues(input, "&")
+}
+
+// encodeURLValues encodes the input joining key-value pairs with the separator.
+func encodeURLValues(input map[string][]string, separator string) string {
+	if len(input) == 0 {
+		return ""
+	}
+
+	return string(appendURLValues(nil, input, separator))
+}
+
+// appendURLValues appends the encoded input to dst joining key-value pairs
+// with the separator, growing dst at most once.
 //
 //nolint:cyclop
-func EncodeURLValues(input map[string][]string) string {
+func appendURLValues(dst []byte, input map[string][]string, separator string) []byte {
 	// short-circuit in the empty case
 	keyCount := len(input)
 	if keyCount == 0 {
-		return ""
+		return dst
 	}
 
-	var charCount, hexCount, keyValuesCount int
+	var charCount, hexCount, entryCount, valueCount int
 
 	keys := make([]string, 0, keyCount)
 	for key, vals := range input {
 		keys = append(keys, key)
-		vsCount := len(vals)
+
+		// A key without values is written once as a flag, unless the key is
+		// empty leaving nothing to write.
+		entries := len(vals)
+		if entries == 0 && key != "" {
+			entries = 1
+		}
 
 		for i := 0; i < len(key); i++ {
 			if encodeQueryComponent.shouldEscape(key[i]) {
-				hexCount += vsCount
+				hexCount += entries
 			}
 		}
 
-		charCount += len(key) * vsCount
-		keyValuesCount += vsCount
+		charCount += len(key) * entries
+		entryCount += entries
+		valueCount += len(vals)
 
 		for _, val := range vals {
 			for i := 0; i < len(val); i++ {
@@ -160,39 +300,65 @@ func EncodeURLValues(input map[string][]string) string {
 		}
 	}
 
+	if entryCount == 0 {
+		return dst
+	}
+
 	required := charCount + // total characters in the keys
 		2*hexCount + // additional characters due to the encoding %xx that's two more x's
-		2*keyValuesCount - 1 // separating & and =
-	result := make([]byte, required)
+		(entryCount-1)*len(separator) + // separators
+		valueCount // = between key and value
+
+	start := len(dst)
+	result := append(dst, make([]byte, required)...)
 
 	sort.Strings(keys)
 
-	pos := 0
+	pos := start
+	wrote := false
 
 	for _, key := range keys {
-		for _, val := range input[key] {
-			if pos > 0 {
-				result[pos] = '&'
-				pos++
+		vals := input[key]
+
+		if len(vals) == 0 {
+			if key == "" {
+				continue
 			}
 
-			pos = escapeInto(key, pos, result)
+			if wrote {
+				pos += copy(result[pos:], separator)
+			}
+
+			pos = escapeInto(key, pos, result, encodeQueryComponent)
+			wrote = true
+
+			continue
+		}
+
+		for _, val := range vals {
+			if wrote {
+				pos += copy(result[pos:], separator)
+			}
+
+			pos = escapeInto(key, pos, result, encodeQueryComponent)
 			result[pos] = '='
-			pos = escapeInto(val, pos+1, result)
+			pos = escapeInto(val, pos+1, result, encodeQueryComponent)
+			wrote = true
 		}
 	}
 
-	return string(result)
+	return result[:pos]
 }
 
 const upperhex = "0123456789ABCDEF"
 
-// escapeInto escapes all of "input", writing the "result" into target
-// starting at index "offset".
-func escapeInto(input string, offset int, target []byte) int {
+// escapeInto escapes all of "input" according to mode, writing the "result"
+// into target starting at index "offset". The target must be sized using the
+// same mode.
+func escapeInto(input string, offset int, target []byte, mode encoding) int {
 	for pos := 0; pos < len(input); pos++ {
 		switch c := input[pos]; {
-		case encodeQueryComponent.shouldEscape(c):
+		case mode.shouldEscape(c):
 			target[offset] = '%'
 			target[offset+1] = upperhex[c>>4]
 			target[offset+2] = upperhex[c&15]
@@ -208,38 +374,129 @@ func escapeInto(input string, offset int, target []byte) int {
 
 // Unescape URL decodes the input.
 func Unescape(input string) (string, error) {
-	// Count how many escaped bytes there are and
-	// guarantee that they are all of 2 characters
-	// in length.
-	var hexCount int
+	result, _, err := unescape(input)
 
-	for i := 0; i < len(input); i++ {
-		if input[i] == '%' {
-			hexCount++
+	return result, err
+}
 
-			i += 2
+// AppendUnescape URL decodes the input like [Unescape], appending the result
+// to dst and returning the extended buffer, so the capacity of dst may be
+// reused across calls. On error dst is returned unmodified along with the
+// [EscapeError].
+func AppendUnescape(dst []byte, input string) ([]byte, error) {
+	hexCount, _, err := countEscapes(input)
+	if err != nil {
+		return dst, err
+	}
 
-			// not enought characters for
-			if i >= len(input) {
-				return "", EscapeError(input[i-2:])
-			}
+	start := len(dst)
+	result := append(dst, make([]byte, len(input)-2*hexCount)...) //nolint:gomnd
+
+	if _, err := unescapeInto(input, start, result); err != nil {
+		return dst, err
+	}
+
+	return result, nil
+}
+
+// DecodedLen returns the length of the input once URL decoded by [Unescape],
+// without decoding it, such as to size the buffer given to [AppendUnescape].
+// A malformed escape returns an [EscapeError] as Unescape would.
+func DecodedLen(input string) (int, error) {
+	if _, err := checkEscapes(input); err != nil {
+		return 0, err
+	}
+
+	// The escapes have already been checked.
+	hexCount, _, _ := countEscapes(input)
+
+	return len(input) - 2*hexCount, nil //nolint:gomnd
+}
+
+// UnescapeLenient URL decodes the input like [Unescape] but never fails, to
+// salvage what it can from the output of buggy clients. Each valid %xx escape
+// is decoded while a malformed one, such as the %2y of bark%2y or a trailing
+// '%', is passed through literally.
+//
+// The decoding is lossy, a literal "%2y" in the result can not be told apart
+// from the escaped "%252y", so the result can not be escaped back into the
+// input. Use [Unescape] wherever the input must be well-formed.
+func UnescapeLenient(input string) string {
+	if strings.IndexByte(input, '%') < 0 {
+		return input
+	}
+
+	result := make([]byte, 0, len(input))
+
+	for pos := 0; pos < len(input); pos++ {
+		if c := input[pos]; c != '%' || pos+2 >= len(input) {
+			result = append(result, c)
+
+			continue
 		}
+
+		gByte := checkValidHexCharacter(input[pos+1])
+		lByte := checkValidHexCharacter(input[pos+2])
+
+		if (gByte|lByte)&hexCharErrorBit != 0 {
+			result = append(result, '%')
+
+			continue
+		}
+
+		result = append(result, gByte<<4+lByte) //nolint:gomnd
+		pos += 2
+	}
+
+	return string(result)
+}
+
+// unescape URL decodes the input. On error the index of the malformed escape
+// is also returned.
+func unescape(input string) (string, int, error) {
+	// Count how many escaped bytes there are and
+	// guarantee that they are all of 2 characters
+	// in length.
+	hexCount, pos, err := countEscapes(input)
+	if err != nil {
+		return "", pos, err
 	}
 
 	// short-circuit in case no unescaping is required
 	if hexCount == 0 {
-		return input, nil
+		return input, 0, nil
 	}
 
 	required := len(input) - 2*hexCount //nolint:gomnd
 	result := make([]byte, required)
 
-	_, err := unescapeInto(input, 0, result)
+	pos, err = unescapeInto(input, 0, result)
 	if err != nil {
-		return "", err
+		return "", pos, err
+	}
+
+	return string(result), 0, nil
+}
+
+// countEscapes counts the escaped bytes, checking each is followed by two
+// characters. On error the index of the truncated escape is returned.
+func countEscapes(input string) (int, int, error) {
+	var hexCount int
+
+	for i := 0; i < len(input); i++ {
+		if input[i] == '%' {
+			hexCount++
+
+			// not enough characters for
+			if i+2 >= len(input) {
+				return 0, i, EscapeError(input[i:])
+			}
+
+			i += 2
+		}
 	}
 
-	return string(result), nil
+	return hexCount, 0, nil
 }
 
 // UnescapeErrorChecker scans the input checking for malformed encoded entities.
@@ -247,32 +504,40 @@ func Unescape(input string) (string, error) {
 // It is a stripped down version of Unescape without actually extracting the parts
 // or decoding the string it returns an error if and only if the aforementioned does.
 func UnescapeErrorChecker(input string) error {
-	l := len(input)
-	if input[l-1] == '%' {
-		return EscapeError("%")
-	} else if input[l-2] == '%' {
-		return EscapeError(input[l-2:])
+	_, err := checkEscapes(input)
+
+	return err
+}
+
+// checkEscapes is [UnescapeErrorChecker] also returning the index of the
+// malformed escape on error.
+func checkEscapes(input string) (int, error) {
+	if _, pos, err := countEscapes(input); err != nil {
+		return pos, err
 	}
 
-	for pos := 0; pos < l; pos++ {
+	for pos := 0; pos < len(input); pos++ {
 		if input[pos] == '%' {
 			gByte := checkValidHexCharacter(input[pos+1])
 			lByte := checkValidHexCharacter(input[pos+2])
 
 			if (gByte|lByte)&hexCharErrorBit != 0 {
-				return EscapeError(input[pos : pos+3])
+				return pos, EscapeError(input[pos : pos+3])
 			}
 
 			pos += 2
 		}
 	}
 
-	return nil
+	return 0, nil
 }
 
 // 10000 = 16 in decimal.
 const hexCharErrorBit byte = 1 << 4
 
+// unescapeInto decodes the input into target starting at index offset,
+// returning the index after the last byte written. On error the index of the
+// malformed escape in input is returned instead.
 func unescapeInto(input string, offset int, target []byte) (int, error) {
 	for pos := 0; pos < len(input); pos++ {
 		switch c := input[pos]; {
@@ -281,7 +546,7 @@ func unescapeInto(input string, offset int, target []byte) (int, error) {
 			lByte := checkValidHexCharacter(input[pos+2])
 
 			if (gByte|lByte)&hexCharErrorBit != 0 {
-				return 0, EscapeError(input[pos : pos+3])
+				return pos, EscapeError(input[pos : pos+3])
 			}
 
 			target[offset] = gByte<<4 + lByte //nolint:gomnd
@@ -317,9 +582,29 @@ func checkValidHexCharacter(hex byte) byte {
 type KeyValueStore interface {
 	// Get returns the first value for the given key. Empty string otherwise.
 	Get(key string) string
+	// GetFold returns the first value for the given key matched
+	// case-insensitively, as with header names. Empty string otherwise.
+	GetFold(key string) string
+	// Keys returns the distinct keys in the order they are encoded.
+	Keys() []string
+	// SortedKeys returns the distinct keys sorted, such as for display.
+	SortedKeys() []string
+	// Values returns all the values for the given key. A flag has no values.
+	Values(key string) []string
+	// ForEach calls fn for each key-value pair, in the order they are
+	// encoded, stopping early if fn returns false. A flag is passed with an
+	// empty value.
+	ForEach(fn func(key, value string) bool)
 	// Encode stringifies the multi-valued map, url encoding keys and values
-	// joining with an ampersand.
+	// joining with an ampersand. Params are joined with a ';' by
+	// [URI.String], see EncodeSep.
 	Encode() string
+	// EncodeSep is like Encode but joins the pairs with the given separator,
+	// such as ';' for params and '&' for headers.
+	EncodeSep(separator string) string
+	// WriteEncoded writes the encoding of Encode to w, joining the pairs with
+	// the given separator, such as ';' for params and '&' for headers.
+	WriteEncoded(w io.Writer, separator string) (int, error)
 	// Len returns the number of distinct keys.
 	Len() int
 	// Empty returns if the store contains no keys.
@@ -328,8 +613,28 @@ type KeyValueStore interface {
 
 // KeyValuePairs stores key to values similar to that of [url.Values]
 // and implements [KeyValueStore].
+//
+// A key mapped to an empty slice is a flag, such as the lr param, and is
+// encoded without an '='.
 type KeyValuePairs map[string][]string
 
+// Pairs builds a [KeyValuePairs] from alternating keys and values, such as
+// Pairs("transport", "tcp", "user", "phone"). A repeated key has each of its
+// values added in order. Pairs panics if given an odd number of arguments.
+func Pairs(kv ...string) KeyValuePairs {
+	if len(kv)%2 == 1 {
+		panic("sip: Pairs given an odd number of arguments")
+	}
+
+	pairs := make(KeyValuePairs, len(kv)/2)
+
+	for i := 0; i < len(kv); i += 2 {
+		pairs.Add(kv[i], kv[i+1])
+	}
+
+	return pairs
+}
+
 // Decode populates the Store with the given data, returing any encoding errors
 // encountered.
 func (m *KeyValuePairs) Decode(input, separator string) error {
@@ -353,10 +658,161 @@ func (m KeyValuePairs) Get(key string) string {
 	return vs[0]
 }
 
+// GetFold returns the first value for the given key matched
+// case-insensitively, as with header names. Empty string otherwise.
+//
+// An exact match is preferred, otherwise of the keys which match the
+// lexicographically smallest is used.
+func (m KeyValuePairs) GetFold(key string) string {
+	if _, ok := m[key]; ok {
+		return m.Get(key)
+	}
+
+	var match string
+
+	found := false
+
+	for other := range m {
+		if strings.EqualFold(other, key) && (!found || other < match) {
+			match = other
+			found = true
+		}
+	}
+
+	return m.Get(match)
+}
+
+// Keys returns the distinct keys sorted, the order they are encoded.
+func (m KeyValuePairs) Keys() []string {
+	return m.SortedKeys()
+}
+
+// SortedKeys returns the distinct keys sorted, the order they are encoded.
+func (m KeyValuePairs) SortedKeys() []string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Values returns all the values for the given key. The returned slice must
+// not be modified.
+func (m KeyValuePairs) Values(key string) []string {
+	return m[key]
+}
+
+// ForEach calls fn for each key-value pair, including repeated keys, stopping
+// early if fn returns false. Keys are visited sorted, matching the order of
+// [KeyValuePairs.Encode], and the values of a key in the order added. A flag
+// is passed once with an empty value.
+func (m KeyValuePairs) ForEach(fn func(key, value string) bool) {
+	for _, key := range m.Keys() {
+		vals := m[key]
+
+		if len(vals) == 0 {
+			if !fn(key, "") {
+				return
+			}
+
+			continue
+		}
+
+		for _, val := range vals {
+			if !fn(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// Set sets the key to value, replacing any existing values.
+func (m KeyValuePairs) Set(key, value string) {
+	m[key] = []string{value}
+}
+
+// Add adds the value to key, appending to any existing values.
+func (m KeyValuePairs) Add(key, value string) {
+	m[key] = append(m[key], value)
+}
+
+// Del deletes the values associated with key.
+func (m KeyValuePairs) Del(key string) {
+	delete(m, key)
+}
+
+// MergeSet copies each key of other into the map, replacing any existing
+// values of that key. Flags are copied as flags.
+func (m KeyValuePairs) MergeSet(other KeyValueStore) {
+	for _, key := range other.Keys() {
+		m[key] = append([]string{}, other.Values(key)...)
+	}
+}
+
+// MergeAdd appends the values of each key of other to any existing values in
+// the map. A flag is only added when the key is not already present.
+func (m KeyValuePairs) MergeAdd(other KeyValueStore) {
+	for _, key := range other.Keys() {
+		values := other.Values(key)
+
+		if _, ok := m[key]; !ok && len(values) == 0 {
+			m[key] = []string{}
+
+			continue
+		}
+
+		m[key] = append(m[key], values...)
+	}
+}
+
+// Clone returns a deep copy of the multi-valued map.
+func (m KeyValuePairs) Clone() KeyValuePairs {
+	if m == nil {
+		return nil
+	}
+
+	clone := make(KeyValuePairs, len(m))
+	for key, vals := range m {
+		clone[key] = append([]string(nil), vals...)
+	}
+
+	return clone
+}
+
 // Encode stringifies the multi-valued map, url encoding keys and values
 // joining with an ampersand.
 func (m KeyValuePairs) Encode() string {
-	return EncodeURLValues(m)
+	return m.EncodeSep("&")
+}
+
+// EncodeSep is like [KeyValuePairs.Encode] but joins the pairs with the given
+// separator, such as ';' for params.
+func (m KeyValuePairs) EncodeSep(separator string) string {
+	return string(m.AppendEncode(nil, separator))
+}
+
+// AppendEncode appends the encoding of [KeyValuePairs.Encode] to dst, joining
+// the pairs with the given separator, and returns the extended buffer. This
+// avoids the intermediate string when composing a larger buffer.
+func (m KeyValuePairs) AppendEncode(dst []byte, separator string) []byte {
+	return appendURLValues(dst, m, separator)
+}
+
+// WriteEncoded writes the encoding of [KeyValuePairs.Encode] to w, joining the
+// pairs with the given separator.
+func (m KeyValuePairs) WriteEncoded(w io.Writer, separator string) (int, error) {
+	if len(m) == 0 {
+		return 0, nil
+	}
+
+	return w.Write(m.AppendEncode(nil, separator)) //nolint:wrapcheck
 }
 
 // Len returns the number of distinct keys.
@@ -383,12 +839,46 @@ func (EmptyStore) Get(_ string) string {
 	return ""
 }
 
+// GetFold returns the first value for the given key matched
+// case-insensitively. Empty string otherwise.
+func (EmptyStore) GetFold(_ string) string {
+	return ""
+}
+
+// Keys returns the distinct keys, always none.
+func (EmptyStore) Keys() []string {
+	return nil
+}
+
+// SortedKeys returns the distinct keys sorted, always none.
+func (EmptyStore) SortedKeys() []string {
+	return nil
+}
+
+// Values returns all the values for the given key, always none.
+func (EmptyStore) Values(_ string) []string {
+	return nil
+}
+
+// ForEach calls fn for each key-value pair, of which there are none.
+func (EmptyStore) ForEach(_ func(key, value string) bool) {}
+
 // Encode stringifies the multi-valued map, url encoding keys and values
 // joining with an ampersand.
 func (EmptyStore) Encode() string {
 	return ""
 }
 
+// EncodeSep returns an empty string as the store is always empty.
+func (EmptyStore) EncodeSep(_ string) string {
+	return ""
+}
+
+// WriteEncoded writes nothing as the store is always empty.
+func (EmptyStore) WriteEncoded(_ io.Writer, _ string) (int, error) {
+	return 0, nil
+}
+
 // Len returns the number of distinct keys.
 func (EmptyStore) Len() int {
 	return 0
@@ -421,6 +911,44 @@ func (s *LazyStore) Get(key string) string {
 	return s.KeyValuePairs.Get(key)
 }
 
+// GetFold returns the first value for the given key matched
+// case-insensitively. Empty string otherwise.
+func (s *LazyStore) GetFold(key string) string {
+	s.load()
+
+	return s.KeyValuePairs.GetFold(key)
+}
+
+// Keys returns the distinct keys sorted, the order they are encoded.
+func (s *LazyStore) Keys() []string {
+	s.load()
+
+	return s.KeyValuePairs.Keys()
+}
+
+// SortedKeys returns the distinct keys sorted, the order they are encoded.
+func (s *LazyStore) SortedKeys() []string {
+	s.load()
+
+	return s.KeyValuePairs.SortedKeys()
+}
+
+// Values returns all the values for the given key. The returned slice must
+// not be modified.
+func (s *LazyStore) Values(key string) []string {
+	s.load()
+
+	return s.KeyValuePairs.Values(key)
+}
+
+// ForEach calls fn for each key-value pair, stopping early if fn returns
+// false. See [KeyValuePairs.ForEach] for the order.
+func (s *LazyStore) ForEach(fn func(key, value string) bool) {
+	s.load()
+
+	s.KeyValuePairs.ForEach(fn)
+}
+
 // Encode stringifies the multi-valued map, url encoding keys and values
 // joining with an ampersand.
 func (s *LazyStore) Encode() string {
@@ -429,6 +957,22 @@ func (s *LazyStore) Encode() string {
 	return s.KeyValuePairs.Encode()
 }
 
+// EncodeSep loads the store and returns the encoding of [LazyStore.Encode]
+// joining the pairs with the given separator.
+func (s *LazyStore) EncodeSep(separator string) string {
+	s.load()
+
+	return s.KeyValuePairs.EncodeSep(separator)
+}
+
+// WriteEncoded loads the store and writes the encoding of
+// [LazyStore.Encode] to w, joining the pairs with the given separator.
+func (s *LazyStore) WriteEncoded(w io.Writer, separator string) (int, error) {
+	s.load()
+
+	return s.KeyValuePairs.WriteEncoded(w, separator)
+}
+
 // Len returns the number of distinct keys.
 func (s *LazyStore) Len() int {
 	s.load()
@@ -445,6 +989,53 @@ func (s *LazyStore) Empty() bool {
 	return s.input == ""
 }
 
+// Raw returns the input of the store as given to [LazyStore.Decode], still
+// encoded. The bool reports if the store is yet to be loaded, otherwise the
+// input has been discarded and the contents may have been modified.
+func (s *LazyStore) Raw() (string, bool) {
+	return s.input, s.KeyValuePairs == nil
+}
+
+// peek returns the first value for the given key like [LazyStore.Get] but
+// scans the input rather than loading the store, unless a key is escaped.
+func (s *LazyStore) peek(key string) string {
+	if s.KeyValuePairs != nil {
+		return s.KeyValuePairs.Get(key)
+	}
+
+	for input := s.input; input != ""; {
+		var pair string
+
+		pair, input, _ = strings.Cut(input, s.separator)
+		rawKey, rawValue, hasValue := strings.Cut(pair, "=")
+
+		if strings.IndexByte(rawKey, '%') >= 0 {
+			return s.Get(key)
+		}
+
+		if rawKey != key || !hasValue {
+			continue
+		}
+
+		// Any possible errors have already been checked in the Decode
+		// call to [UnescapeErrorChecker].
+		value, _ := Unescape(rawValue)
+
+		return value
+	}
+
+	return ""
+}
+
+// clone returns a copy of the store that shares no mutable state.
+func (s *LazyStore) clone() *LazyStore {
+	return &LazyStore{
+		KeyValuePairs: s.KeyValuePairs.Clone(),
+		input:         s.input,
+		separator:     s.separator,
+	}
+}
+
 func (s *LazyStore) load() {
 	if s.KeyValuePairs != nil {
 		return
@@ -458,3 +1049,109 @@ func (s *LazyStore) load() {
 	s.input = ""
 	s.separator = ""
 }
+
+// cloneStore returns a deep copy of the known store implementations. Unknown
+// implementations are returned as is.
+func cloneStore(store KeyValueStore) KeyValueStore {
+	switch store := store.(type) {
+	case KeyValuePairs:
+		return store.Clone()
+	case *LazyStore:
+		return store.clone()
+	case OrderedPairs:
+		return store.Clone()
+	default:
+		return store
+	}
+}
+
+// storeWith returns a copy of the store with the value added to key, or
+// replacing the values of key when replace is set. The order of an
+// [OrderedPairs] store is kept, all other stores are converted to
+// [KeyValuePairs].
+func storeWith(store KeyValueStore, key, value string, replace bool) KeyValueStore {
+	if ordered, ok := store.(OrderedPairs); ok {
+		ordered = ordered.Clone()
+
+		if replace {
+			ordered.Set(key, value)
+		} else {
+			ordered.Add(key, value)
+		}
+
+		return ordered
+	}
+
+	pairs := pairsOf(store).Clone()
+	if pairs == nil {
+		pairs = make(KeyValuePairs, 1)
+	}
+
+	if replace {
+		pairs.Set(key, value)
+	} else {
+		pairs.Add(key, value)
+	}
+
+	return pairs
+}
+
+// storeWithout returns a copy of the store without the key. As with
+// [storeWith] the order of an [OrderedPairs] store is kept.
+func storeWithout(store KeyValueStore, key string) KeyValueStore {
+	if ordered, ok := store.(OrderedPairs); ok {
+		ordered = ordered.Clone()
+		ordered.Del(key)
+
+		return ordered
+	}
+
+	pairs := pairsOf(store).Clone()
+	if pairs == nil {
+		return EmptyStore{}
+	}
+
+	pairs.Del(key)
+
+	return pairs
+}
+
+// pairsOf returns the contents of the store as a [KeyValuePairs]. The result
+// may share memory with the store so must not be modified.
+func pairsOf(store KeyValueStore) KeyValuePairs {
+	switch store := store.(type) {
+	case KeyValuePairs:
+		return store
+	case *LazyStore:
+		store.load()
+
+		return store.KeyValuePairs
+	case EmptyStore:
+		return nil
+	case OrderedPairs:
+		pairs := make(KeyValuePairs, len(store))
+
+		for _, pair := range store {
+			if pair.Flag {
+				if _, ok := pairs[pair.Key]; !ok {
+					pairs[pair.Key] = []string{}
+				}
+
+				continue
+			}
+
+			pairs.Add(pair.Key, pair.Value)
+		}
+
+		return pairs
+	default:
+		if store.Empty() {
+			return nil
+		}
+
+		// Encode joins the pairs with an ampersand.
+		pairs, _ := DecodeURLValues(store.Encode(), "&")
+
+		return pairs
+	}
+}
@@ -3,6 +3,7 @@ package sipuri_test
 import (
 	"errors"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/percivalalb/sipuri"
@@ -17,6 +18,115 @@ func TestURLEncodeURLValues(t *testing.T) {
 	equalF(t, testQueryString, got, "encodeURLValues(%v) = %q want %q", query, got, testQueryString)
 }
 
+func TestWriteEncoded(t *testing.T) {
+	t.Parallel()
+
+	lazy := new(sipuri.LazyStore)
+	if err := lazy.Decode("x=2;transport=tcp;lr;x=1", ";"); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	ordered, err := sipuri.DecodeURLValuesOrdered("x=2;transport=tcp;lr;x=1", ";")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	type test struct {
+		store     sipuri.KeyValueStore
+		separator string
+		expect    string
+		msg       string
+	}
+
+	tests := []test{
+		{sipuri.Pairs("transport", "tcp", "user", "phone"), ";", "transport=tcp;user=phone", "params"},
+		{sipuri.Pairs("subject", "project x", "priority", "urgent"), "&", "priority=urgent&subject=project%20x", "headers"},
+		{lazy, ";", "lr;transport=tcp;x=2;x=1", "lazy store"},
+		{ordered, ";", "x=2;transport=tcp;lr;x=1", "ordered store"},
+		{sipuri.EmptyStore{}, ";", "", "empty store"},
+		{sipuri.KeyValuePairs{}, ";", "", "empty pairs"},
+	}
+
+	for _, test := range tests {
+		var builder strings.Builder
+
+		n, err := test.store.WriteEncoded(&builder, test.separator)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, test.expect, builder.String(), "encoded %s", test.msg)
+		equalF(t, len(test.expect), n, "bytes written for %s", test.msg)
+		equalF(t, test.expect, test.store.EncodeSep(test.separator), "encode sep of %s", test.msg)
+	}
+}
+
+func TestPairs(t *testing.T) {
+	t.Parallel()
+
+	equalF(t, sipuri.KeyValuePairs{}, sipuri.Pairs(), "no arguments")
+	equalF(t, sipuri.KeyValuePairs{"transport": {"tcp"}, "user": {"phone"}}, sipuri.Pairs("transport", "tcp", "user", "phone"), "even arguments")
+	equalF(t, sipuri.KeyValuePairs{"x": {"1", "2"}}, sipuri.Pairs("x", "1", "x", "2"), "repeated key")
+
+	uri := sipuri.New("alice", "atlanta.com", sipuri.WithParams(sipuri.Pairs("transport", "tcp")))
+	equalF(t, "sip:alice@atlanta.com;transport=tcp", uri.String(), "used as params")
+
+	defer func() {
+		equalF(t, "sip: Pairs given an odd number of arguments", recover(), "odd arguments panic")
+	}()
+
+	sipuri.Pairs("transport", "tcp", "user")
+	t.Fatalf("expected panic")
+}
+
+func TestKeyValuePairsAppendEncode(t *testing.T) {
+	t.Parallel()
+
+	query := sipuri.KeyValuePairs(getTestURLValues())
+
+	equalF(t, "sip:alice@atlanta.com?"+testQueryString, string(query.AppendEncode([]byte("sip:alice@atlanta.com?"), "&")), "appended to prefix")
+	equalF(t, query.Encode(), string(query.AppendEncode(nil, "&")), "same as encode")
+
+	params := sipuri.KeyValuePairs{"lr": {}, "transport": {"tcp"}, "x": {"1", "2"}}
+
+	equalF(t, "lr;transport=tcp;x=1;x=2", string(params.AppendEncode(nil, ";")), "params separator")
+	equalF(t, "lr, transport=tcp, x=1, x=2", string(params.AppendEncode(nil, ", ")), "multi-byte separator")
+	equalF(t, "sip:h", string(sipuri.KeyValuePairs{}.AppendEncode([]byte("sip:h"), ";")), "empty appends nothing")
+}
+
+func TestURLEncodeURLValuesFlags(t *testing.T) {
+	t.Parallel()
+
+	query := sipuri.KeyValuePairs{
+		"lr":        {},
+		"ob":        nil,
+		"transport": {"tcp"},
+		"empty":     {""},
+	}
+
+	equalF(t, "empty=&lr&ob&transport=tcp", sipuri.EncodeURLValues(query), "flags encoded without =")
+	equalF(t, "lr", sipuri.EncodeURLValues(sipuri.KeyValuePairs{"lr": {}}), "single flag")
+	equalF(t, "a=1", sipuri.EncodeURLValues(sipuri.KeyValuePairs{"": {}, "a": {"1"}}), "empty flag not written")
+	equalF(t, "", sipuri.EncodeURLValues(sipuri.KeyValuePairs{"": {}}), "only empty flag")
+	equalF(t, "=&a=1", sipuri.EncodeURLValues(sipuri.KeyValuePairs{"": {""}, "a": {"1"}}), "empty key with value")
+	equalF(t, "x;a=1", string(sipuri.KeyValuePairs{"": nil, "a": {"1"}}.AppendEncode([]byte("x;"), ";")), "empty flag appended")
+
+	pairs, err := sipuri.DecodeURLValues("lr;;a=1;", ";")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, sipuri.KeyValuePairs{"lr": {}, "a": {"1"}}, pairs, "empty pairs skipped")
+
+	ordered, err := sipuri.DecodeURLValuesOrdered("lr;;a=1;", ";")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, sipuri.OrderedPairs{{Key: "lr", Flag: true}, {Key: "a", Value: "1"}}, ordered, "empty ordered pairs skipped")
+	equalF(t, "a=1", sipuri.OrderedPairs{{Flag: true}, {Key: "a", Value: "1"}}.Encode(), "empty ordered flag not written")
+}
+
 func TestURLDecodeURLValues(t *testing.T) {
 	t.Parallel()
 
@@ -44,9 +154,24 @@ func TestURLDecodeURLValues(t *testing.T) {
 		{
 			"transport",
 			sipuri.KeyValuePairs{
-				"transport": {""},
+				"transport": {},
 			},
-			"singleton",
+			"flag",
+		},
+		{
+			"lr;transport=TCP;lr",
+			sipuri.KeyValuePairs{
+				"lr":        {},
+				"transport": {"TCP"},
+			},
+			"repeated flag",
+		},
+		{
+			"transport=TCP;transport",
+			sipuri.KeyValuePairs{
+				"transport": {"TCP"},
+			},
+			"flag after value",
 		},
 		{
 			"transport=TCP;user=percivalalb;group=polarbear",
@@ -72,6 +197,219 @@ func TestURLDecodeURLValues(t *testing.T) {
 	}
 }
 
+func TestDecodeURLValuesLenient(t *testing.T) {
+	t.Parallel()
+
+	result, errs := sipuri.DecodeURLValuesLenient("transport=tcp;x=%2y;%zz;user=phone;lr", ";")
+
+	equalF(t, sipuri.KeyValuePairs{"transport": {"tcp"}, "user": {"phone"}, "lr": {}}, result, "good pairs kept")
+	equalF(t, 2, len(errs), "error per bad pair")
+
+	for _, err := range errs {
+		if !errors.Is(err, sipuri.EscapeError("")) {
+			t.Fatalf("expected escape error but got %q", err)
+		}
+	}
+
+	equalF(t, `sip: pair 1: sip: invalid URL escape "%2y"`, errs[0].Error(), "bad value")
+	equalF(t, `sip: pair 2: sip: invalid URL escape "%zz"`, errs[1].Error(), "bad key")
+
+	result, errs = sipuri.DecodeURLValuesLenient("a=1&b=2", "&")
+	equalF(t, sipuri.KeyValuePairs{"a": {"1"}, "b": {"2"}}, result, "well-formed input")
+	equalF(t, []error(nil), errs, "no errors")
+}
+
+func TestKeyValuePairsModify(t *testing.T) {
+	t.Parallel()
+
+	pairs := make(sipuri.KeyValuePairs)
+
+	pairs.Set("transport", "udp")
+	pairs.Set("transport", "tcp")
+	equalF(t, sipuri.KeyValuePairs{"transport": {"tcp"}}, pairs, "set replaces values")
+
+	pairs.Add("user", "phone")
+	pairs.Add("transport", "tls")
+	equalF(t, sipuri.KeyValuePairs{"transport": {"tcp", "tls"}, "user": {"phone"}}, pairs, "add appends values")
+
+	pairs.Del("transport")
+	pairs.Del("missing")
+	equalF(t, sipuri.KeyValuePairs{"user": {"phone"}}, pairs, "del removes key")
+
+	equalF(t, "user=phone", pairs.Encode(), "encode after modification")
+}
+
+func TestKeyValuePairsMerge(t *testing.T) {
+	t.Parallel()
+
+	override := sipuri.KeyValuePairs{"transport": {"tcp"}, "lr": {}, "x": {"1", "2"}}
+
+	empty := sipuri.KeyValuePairs{}
+	empty.MergeSet(override)
+	equalF(t, override, empty, "set into empty map")
+
+	empty = sipuri.KeyValuePairs{}
+	empty.MergeAdd(override)
+	equalF(t, override, empty, "add into empty map")
+
+	set := sipuri.KeyValuePairs{"transport": {"udp"}, "x": {"0"}, "ttl": {"1"}}
+	set.MergeSet(override)
+	equalF(t, "lr&transport=tcp&ttl=1&x=1&x=2", set.Encode(), "set over existing keys")
+
+	add := sipuri.KeyValuePairs{"transport": {"udp"}, "x": {"0"}, "lr": {"on"}}
+	add.MergeAdd(override)
+	equalF(t, "lr=on&transport=udp&transport=tcp&x=0&x=1&x=2", add.Encode(), "add over existing keys")
+
+	ordered := sipuri.OrderedPairs{{Key: "b", Value: "1"}, {Key: "a", Value: "2"}}
+	set.MergeSet(ordered)
+	equalF(t, "a=2&b=1&lr&transport=tcp&ttl=1&x=1&x=2", set.Encode(), "set from another store")
+
+	set.MergeAdd(sipuri.EmptyStore{})
+	equalF(t, "a=2&b=1&lr&transport=tcp&ttl=1&x=1&x=2", set.Encode(), "add from empty store")
+
+	override.Add("x", "3")
+	equalF(t, []string{"1", "2"}, empty["x"], "merged values not shared")
+}
+
+func TestGetFold(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice@atlanta.com?Subject=project%20x&to=bob&TO=carol")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "project x", uri.Headers().GetFold("subject"), "lower case lookup")
+		equalF(t, "project x", uri.Headers().GetFold("SUBJECT"), "upper case lookup")
+		equalF(t, "", uri.Headers().Get("subject"), "get remains case-sensitive")
+		equalF(t, "bob", uri.Headers().GetFold("to"), "exact match preferred")
+		equalF(t, "carol", uri.Headers().GetFold("To"), "smallest match used")
+		equalF(t, "", uri.Headers().GetFold("from"), "missing key")
+		equalF(t, "", uri.Params().GetFold("transport"), "empty store")
+
+		equalF(t, "sip:alice@atlanta.com?Subject=project%20x&TO=carol&to=bob", uri.String(), "original casing encoded")
+	}
+
+	ordered := sipuri.OrderedPairs{{Key: "Subject", Value: "x"}}
+	equalF(t, "x", ordered.GetFold("SUBJECT"), "ordered pairs lookup")
+}
+
+func TestStoreKeysValues(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice@atlanta.com;b=1;lr;a=2;b=3")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		params := uri.Params()
+		equalF(t, []string{"a", "b", "lr"}, params.Keys(), "keys sorted")
+		equalF(t, []string{"a", "b", "lr"}, params.SortedKeys(), "sorted keys")
+		equalF(t, []string{"1", "3"}, params.Values("b"), "all values")
+		equalF(t, 0, len(params.Values("lr")), "flag has no values")
+		equalF(t, []string(nil), params.Values("missing"), "missing key")
+
+		equalF(t, []string(nil), uri.Headers().Keys(), "empty store keys")
+		equalF(t, []string(nil), uri.Headers().SortedKeys(), "empty store sorted keys")
+		equalF(t, []string(nil), uri.Headers().Values("a"), "empty store values")
+	}
+}
+
+func TestStoreForEach(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice@atlanta.com;b=1;lr;a=2;b=3")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		var visited []string
+
+		uri.Params().ForEach(func(key, value string) bool {
+			visited = append(visited, key+"="+value)
+
+			return true
+		})
+
+		equalF(t, []string{"a=2", "b=1", "b=3", "lr="}, visited, "all pairs in encoded order")
+
+		visited = nil
+
+		uri.Params().ForEach(func(key, value string) bool {
+			visited = append(visited, key+"="+value)
+
+			return key != "b"
+		})
+
+		equalF(t, []string{"a=2", "b=1"}, visited, "stops early")
+
+		uri.Headers().ForEach(func(key, value string) bool {
+			t.Fatalf("unexpected pair %s=%s in empty store", key, value)
+
+			return true
+		})
+	}
+
+	var visited []string
+
+	sipuri.OrderedPairs{{Key: "b", Value: "1"}, {Key: "lr", Flag: true}, {Key: "a", Value: "2"}}.ForEach(func(key, value string) bool {
+		visited = append(visited, key+"="+value)
+
+		return true
+	})
+
+	equalF(t, []string{"b=1", "lr=", "a=2"}, visited, "ordered pairs in order")
+}
+
+func TestEscape(t *testing.T) {
+	t.Parallel()
+
+	const input = "a b;c=d&e+f:g@h/i?j[k]"
+
+	equalF(t, "a%20b;c=d&e+f%3Ag%40h/i?j%5Bk%5D", sipuri.Escape(input, sipuri.User), "user")
+	equalF(t, "a%20b%3Bc=d&e+f%3Ag%40h%2Fi%3Fj%5Bk%5D", sipuri.Escape(input, sipuri.Password), "password")
+	equalF(t, "a%20b%3Bc%3Dd%26e%2Bf:g%40h%2Fi%3Fj[k]", sipuri.Escape(input, sipuri.Host), "host")
+	equalF(t, "a%20b%3Bc%3Dd%26e%2Bf%3Ag%40h%2Fi%3Fj%5Bk%5D", sipuri.Escape(input, sipuri.Param), "param")
+	equalF(t, sipuri.Escape(input, sipuri.Param), sipuri.Escape(input, sipuri.Header), "header")
+	equalF(t, sipuri.Escape(input, sipuri.Param), sipuri.Escape(input, sipuri.Component(9)), "unknown component")
+	equalF(t, "alice-_.~", sipuri.Escape("alice-_.~", sipuri.Param), "unreserved not escaped")
+
+	uri := sipuri.New(input, "atlanta.com")
+	equalF(t, "sip:"+sipuri.Escape(input, sipuri.User)+"@atlanta.com", uri.String(), "matches string")
+}
+
+func TestEscapePlus(t *testing.T) {
+	t.Parallel()
+
+	const number = "+1-212-555-1212"
+
+	equalF(t, number, sipuri.Escape(number, sipuri.User), "kept in user")
+	equalF(t, "%2B1-212-555-1212", sipuri.Escape(number, sipuri.Param), "escaped in param")
+	equalF(t, "%2B1-212-555-1212", sipuri.Escape(number, sipuri.Header), "escaped in header")
+
+	uri := sipuri.New(number, "gateway.com",
+		sipuri.WithParams(sipuri.KeyValuePairs{"x": {number}}),
+		sipuri.WithHeaders(sipuri.KeyValuePairs{"h": {number}}),
+	)
+	equalF(t, "sip:+1-212-555-1212@gateway.com;x=%2B1-212-555-1212?h=%2B1-212-555-1212", uri.String(), "string")
+
+	for _, parse := range parseFuncs {
+		for _, input := range []string{uri.String(), "sip:%2B1-212-555-1212@gateway.com;x=+1-212-555-1212?h=+1-212-555-1212"} {
+			parsed, err := parse(input)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, number, parsed.User(), "user of %q", input)
+			equalF(t, number, parsed.Params().Get("x"), "param of %q", input)
+			equalF(t, number, parsed.Headers().Get("h"), "header of %q", input)
+		}
+	}
+}
+
 func TestUnescape(t *testing.T) {
 	t.Parallel()
 
@@ -81,6 +419,99 @@ func TestUnescape(t *testing.T) {
 	equalF(t, expect, got, "Unescape(%q) = %q want %q", testQueryString, got, expect)
 }
 
+func TestAppendUnescape(t *testing.T) {
+	t.Parallel()
+
+	expect := "cat=meow&dog=bark!&dog=woof@&mouse=ee  eeΔ&parrot=(hellow)"
+
+	got, err := sipuri.AppendUnescape([]byte("query: "), testQueryString)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "query: "+expect, string(got), "appended to prefix")
+
+	buf := make([]byte, 0, 64)
+
+	buf, err = sipuri.AppendUnescape(buf[:0], "j%40s0n")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "j@s0n", string(buf), "decoded into buffer")
+
+	buf, err = sipuri.AppendUnescape(buf, "bark%2y")
+	if !errors.Is(err, sipuri.EscapeError("%2y")) {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "j@s0n", string(buf), "buffer unmodified on error")
+
+	for _, input := range []string{"a", "%", "%%2", "%2%", "%xx%", "%20%2", "%20"} {
+		_, expectErr := sipuri.Unescape(input)
+		_, err = sipuri.AppendUnescape(nil, input)
+
+		equalF(t, expectErr, err, "same error as unescape for %q", input)
+	}
+}
+
+func TestDecodedLen(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{testQueryString, "j%40s0n", "%CE%94", "plain", ""} {
+		decoded, err := sipuri.Unescape(input)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		length, err := sipuri.DecodedLen(input)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, len(decoded), length, "decoded length of %q", input)
+
+		buf, _ := sipuri.AppendUnescape(make([]byte, 0, length), input)
+		equalF(t, length, cap(buf), "pre-sized buffer not grown for %q", input)
+	}
+
+	for _, input := range []string{"bark%2y", "bark%2", "bark%"} {
+		_, expectErr := sipuri.Unescape(input)
+		_, err := sipuri.DecodedLen(input)
+
+		equalF(t, expectErr, err, "same error as unescape for %q", input)
+	}
+}
+
+func TestUnescapeLenient(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		input  string
+		expect string
+		msg    string
+	}
+
+	tests := []test{
+		{"j%40s0n", "j@s0n", "valid escape"},
+		{"bark%2y", "bark%2y", "invalid hex"},
+		{"bark%2", "bark%2", "truncated escape"},
+		{"bark%", "bark%", "trailing percent"},
+		{"%%41", "%A", "percent before valid escape"},
+		{"a%zz%20b", "a%zz b", "invalid then valid escape"},
+		{"plain", "plain", "no escapes"},
+		{"", "", "empty"},
+	}
+
+	for _, test := range tests {
+		equalF(t, test.expect, sipuri.UnescapeLenient(test.input), "lenient mismatch in %s", test.msg)
+
+		if strict, err := sipuri.Unescape(test.input); err == nil {
+			equalF(t, strict, sipuri.UnescapeLenient(test.input), "same as strict in %s", test.msg)
+		}
+	}
+}
+
 func TestUnescapeError(t *testing.T) {
 	t.Parallel()
 
@@ -107,6 +538,12 @@ func TestUnescapeError(t *testing.T) {
 	}
 
 	equalF(t, err, sipuri.UnescapeErrorChecker("bark%"), "checker matches")
+
+	for _, input := range []string{"a", "%", "%%2", "%2%", "%xx%", "%20%2"} {
+		_, err = sipuri.Unescape(input)
+
+		equalF(t, err, sipuri.UnescapeErrorChecker(input), "checker matches for %q", input)
+	}
 }
 
 // func FuzzReverse(f *testing.F) {
@@ -147,6 +584,34 @@ func BenchmarkEncodeURLValues(b *testing.B) {
 	}
 }
 
+func BenchmarkKeyValuePairs_Encode(b *testing.B) {
+	query := sipuri.KeyValuePairs(getTestURLValues())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var buf []byte
+
+	for i := 0; i < b.N; i++ {
+		buf = append(buf[:0], "sip:alice@atlanta.com?"...)
+		buf = append(buf, query.Encode()...)
+	}
+}
+
+func BenchmarkKeyValuePairs_AppendEncode(b *testing.B) {
+	query := sipuri.KeyValuePairs(getTestURLValues())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var buf []byte
+
+	for i := 0; i < b.N; i++ {
+		buf = append(buf[:0], "sip:alice@atlanta.com?"...)
+		buf = query.AppendEncode(buf, "&")
+	}
+}
+
 func BenchmarkURLUnescape(b *testing.B) {
 	b.ResetTimer()
 
@@ -156,6 +621,7 @@ func BenchmarkURLUnescape(b *testing.B) {
 }
 
 func BenchmarkUnescape(b *testing.B) {
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -163,6 +629,17 @@ func BenchmarkUnescape(b *testing.B) {
 	}
 }
 
+func BenchmarkAppendUnescape(b *testing.B) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var buf []byte
+
+	for i := 0; i < b.N; i++ {
+		buf, _ = sipuri.AppendUnescape(buf[:0], testQueryString)
+	}
+}
+
 const testQueryString = "cat=meow&dog=bark%21&dog=woof%40&mouse=ee%20%20ee%CE%94&parrot=%28hellow%29"
 
 func getTestURLValues() url.Values {
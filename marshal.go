@@ -0,0 +1,130 @@
+package sipuri
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON encodes the URI as a JSON string of its [URI.String] form.
+//
+// A URI without a host, such as the zero value, can not be parsed back so a
+// [MalformedURIError] with the [MissingHost] cause is returned instead.
+func (sipURI URI) MarshalJSON() ([]byte, error) {
+	if sipURI.host == "" {
+		return nil, MalformedURIError{Cause: MissingHost}
+	}
+
+	return json.Marshal(sipURI.String()) //nolint:wrapcheck
+}
+
+// UnmarshalJSON decodes a JSON string through [Parse]. A JSON null leaves the
+// URI unchanged.
+//
+// Any error from [Parse] is returned as is.
+func (sipURI *URI) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var uri string
+	if err := json.Unmarshal(data, &uri); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	parsed, err := Parse(uri)
+	if err != nil {
+		return err
+	}
+
+	*sipURI = *parsed
+
+	return nil
+}
+
+// MarshalBinary encodes the URI as the UTF-8 bytes of its [URI.String] form,
+// implementing [encoding.BinaryMarshaler].
+//
+// As with [URI.MarshalJSON] a URI without a host returns a
+// [MalformedURIError] with the [MissingHost] cause.
+func (sipURI URI) MarshalBinary() ([]byte, error) {
+	if sipURI.host == "" {
+		return nil, MalformedURIError{Cause: MissingHost}
+	}
+
+	return sipURI.AppendString(nil), nil
+}
+
+// UnmarshalBinary decodes the bytes through [Parse], implementing
+// [encoding.BinaryUnmarshaler].
+//
+// Any error from [Parse] is returned as is.
+func (sipURI *URI) UnmarshalBinary(data []byte) error {
+	parsed, err := ParseBytes(data)
+	if err != nil {
+		return err
+	}
+
+	*sipURI = *parsed
+
+	return nil
+}
+
+// Scan implements [database/sql.Scanner], parsing a string or []byte column
+// through [Parse]. A NULL leaves the zero value URI.
+//
+// Any error from [Parse] is wrapped.
+func (sipURI *URI) Scan(src interface{}) error {
+	var uri string
+
+	switch src := src.(type) {
+	case nil:
+		*sipURI = URI{}
+
+		return nil
+	case string:
+		uri = src
+	case []byte:
+		uri = string(src)
+	default:
+		return fmt.Errorf("sip: scan: unsupported type %T", src) //nolint:goerr113
+	}
+
+	parsed, err := ParseValue(uri)
+	if err != nil {
+		return fmt.Errorf("sip: scan: %w", err)
+	}
+
+	*sipURI = parsed
+
+	return nil
+}
+
+// Value implements [driver.Valuer] returning the [URI.String] form. A URI
+// without a host, such as the zero value, is stored as NULL.
+func (sipURI URI) Value() (driver.Value, error) {
+	if sipURI.host == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	return sipURI.String(), nil
+}
+
+// MarshalText encodes the protocol as its scheme name, implementing
+// [encoding.TextMarshaler].
+func (p Protocol) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes the scheme name through [ParseScheme], implementing
+// [encoding.TextUnmarshaler]. An unknown scheme returns [ErrInvalidScheme].
+func (p *Protocol) UnmarshalText(data []byte) error {
+	proto, ok := ParseScheme(string(data))
+	if !ok {
+		return ErrInvalidScheme
+	}
+
+	*p = proto
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package sipuri_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestJSON(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		Contact sipuri.URI  `json:"contact"`
+		Route   *sipuri.URI `json:"route"`
+	}
+
+	input := `{"contact":"sip:alice:@atlanta.com;transport=tcp?subject=project%20x","route":null}`
+
+	var decoded payload
+	if err := json.Unmarshal([]byte(input), &decoded); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "alice", decoded.Contact.User(), "user mismatch")
+	equalF(t, "tcp", decoded.Contact.Params().Get("transport"), "param mismatch")
+	equalF(t, (*sipuri.URI)(nil), decoded.Route, "null route")
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, input, string(encoded), "round trip")
+}
+
+func TestJSONError(t *testing.T) {
+	t.Parallel()
+
+	var uri sipuri.URI
+
+	err := json.Unmarshal([]byte(`"sip:@atlanta.com"`), &uri)
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MissingUser}) {
+		t.Fatalf("expected missing user error but got %q", err)
+	}
+
+	err = json.Unmarshal([]byte(`"http://atlanta.com"`), &uri)
+	if !errors.Is(err, sipuri.ErrInvalidScheme) {
+		t.Fatalf("expected invalid scheme error but got %q", err)
+	}
+
+	if err = json.Unmarshal([]byte(`5060`), &uri); err == nil {
+		t.Fatalf("expected error decoding a number")
+	}
+
+	// The zero value has no host so can not be marshalled.
+	_, err = json.Marshal(sipuri.URI{})
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MissingHost}) {
+		t.Fatalf("expected missing host error but got %q", err)
+	}
+}
+
+func TestBinary(t *testing.T) {
+	t.Parallel()
+
+	inputs := []string{
+		"sip:alice@atlanta.com",
+		"sip:alice:@atlanta.com;transport=tcp?subject=project%20x",
+		"sips:alice:secret@[2001:db8::2:1]:5061;lr",
+	}
+
+	for _, input := range inputs {
+		uri, err := sipuri.Parse(input)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		data, err := uri.MarshalBinary()
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, input, string(data), "marshalled bytes")
+
+		var decoded sipuri.URI
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, *uri, decoded, "round trip %s", input)
+		equalF(t, input, decoded.String(), "reconstructing string %s", input)
+	}
+
+	var uri sipuri.URI
+
+	err := uri.UnmarshalBinary([]byte("sip:@atlanta.com"))
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MissingUser}) {
+		t.Fatalf("expected missing user error but got %q", err)
+	}
+
+	_, err = sipuri.URI{}.MarshalBinary()
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MissingHost}) {
+		t.Fatalf("expected missing host error but got %q", err)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	t.Parallel()
+
+	const input = "sip:alice:@atlanta.com;transport=tcp"
+
+	for _, src := range []interface{}{input, []byte(input)} {
+		var uri sipuri.URI
+		if err := uri.Scan(src); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, input, uri.String(), "scanned %T", src)
+
+		value, err := uri.Value()
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, input, value, "value of %T", src)
+	}
+
+	uri := sipuri.New("alice", "atlanta.com")
+	if err := uri.Scan(nil); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, sipuri.URI{}, uri, "null scanned to zero value")
+
+	value, err := uri.Value()
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, nil, value, "zero value is null")
+
+	err = uri.Scan("sip:@atlanta.com")
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MissingUser}) {
+		t.Fatalf("expected missing user error but got %q", err)
+	}
+
+	if err = uri.Scan(5060); err == nil {
+		t.Fatalf("expected error scanning a number")
+	}
+}
+
+func TestProtocolText(t *testing.T) {
+	t.Parallel()
+
+	type payload struct {
+		Schemes []sipuri.Protocol `json:"schemes"`
+	}
+
+	input := `{"schemes":["sip","sips"]}`
+
+	var decoded payload
+	if err := json.Unmarshal([]byte(input), &decoded); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, []sipuri.Protocol{sipuri.SIP, sipuri.SIPS}, decoded.Schemes, "decoded schemes")
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, input, string(encoded), "round trip")
+
+	if err := json.Unmarshal([]byte(`{"schemes":["tel"]}`), &decoded); !errors.Is(err, sipuri.ErrInvalidScheme) {
+		t.Fatalf("expected invalid scheme error but got %q", err)
+	}
+}
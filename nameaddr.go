@@ -0,0 +1,192 @@
+package sipuri
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedNameAddr is returned when the display name or angle brackets
+// enclosing the URI of a name-addr are malformed.
+var ErrMalformedNameAddr = errors.New("sip: malformed name-addr")
+
+// IsWildcard reports if the value of a Contact header field is the "*" of
+// §10.2.2, ignoring surrounding whitespace, which removes all registrations
+// in a REGISTER request. It is not a URI so [Parse] and [ParseNameAddr] return
+// [ErrInvalidScheme] for it, check IsWildcard first.
+func IsWildcard(input string) bool {
+	return strings.TrimSpace(input) == "*"
+}
+
+// ParseNameAddr parses the value of a Contact, To, From or similar header
+// field, such as:
+//
+//	"Alice" <sip:alice@atlanta.com;transport=tcp>;expires=3600
+//
+// The display name is optional and may be quoted, in which case it is
+// unquoted. The URI may also be given bare, without angle brackets, in which
+// case §20.10 treats any params as header-field params rather than URI
+// params, so the URI ends at the first ';'.
+//
+// The header-field params following the URI are returned separately. Any
+// [MalformedURIError] from [Parse] has its offset relative to the input.
+//
+//nolint:cyclop,funlen
+func ParseNameAddr(input string) (string, *URI, KeyValuePairs, error) {
+	var displayName string
+
+	// Each remainder is a suffix of the trimmed input, giving its offset.
+	trimmed := strings.TrimRight(input, " \t")
+	offset := func(suffix string) int { return len(trimmed) - len(suffix) }
+
+	rest := strings.TrimLeft(trimmed, " \t")
+
+	switch {
+	case strings.HasPrefix(rest, `"`):
+		name, after, ok := unquote(rest)
+		if !ok {
+			return "", nil, nil, ErrMalformedNameAddr
+		}
+
+		displayName, rest = name, strings.TrimLeft(after, " \t")
+
+		if !strings.HasPrefix(rest, "<") {
+			return "", nil, nil, ErrMalformedNameAddr
+		}
+	case strings.Contains(rest, "<"):
+		end := strings.IndexByte(rest, '<')
+		displayName, rest = strings.TrimRight(rest[:end], " \t"), rest[end:]
+	}
+
+	var uri, params string
+
+	uriAt := offset(rest)
+
+	if strings.HasPrefix(rest, "<") {
+		uriAt++
+
+		end := strings.IndexByte(rest, '>')
+		if end < 0 {
+			return "", nil, nil, ErrMalformedNameAddr
+		}
+
+		uri, params = rest[1:end], strings.TrimLeft(rest[end+1:], " \t")
+
+		if params != "" && params[0] != ';' {
+			return "", nil, nil, ErrMalformedNameAddr
+		}
+	} else {
+		end := strings.IndexByte(rest, ';')
+		if end < 0 {
+			end = len(rest)
+		}
+
+		uri, params = rest[:end], rest[end:]
+	}
+
+	sipURI, err := Parse(uri)
+	if err != nil {
+		var malformed MalformedURIError
+		if errors.As(err, &malformed) && malformed.Offset != 0 {
+			malformed.Offset += uriAt
+
+			return "", nil, nil, malformed
+		}
+
+		return "", nil, nil, err
+	}
+
+	if params == "" {
+		return displayName, sipURI, nil, nil
+	}
+
+	headerParams, pos, err := decodeURLValues(params[1:], ";")
+	if err != nil {
+		return "", nil, nil, MalformedURIError{Cause: MalformedParams, Err: err, Offset: offset(params) + 1 + pos}
+	}
+
+	return displayName, sipURI, headerParams, nil
+}
+
+// ParseMany parses a comma-separated list of name-addr values, such as the
+// value of a Route or Record-Route header field:
+//
+//	<sip:p1.example.com;lr>, "Proxy, Two" <sip:p2.example.com;lr>
+//
+// Commas within angle brackets or a quoted display name do not separate
+// elements. Each element is parsed by [ParseNameAddr], discarding its display
+// name and header-field params.
+//
+// The error of the first malformed element is wrapped with its index, with
+// the offset of any [MalformedURIError] relative to the input.
+func ParseMany(input string) ([]*URI, error) {
+	var uris []*URI
+
+	for start := 0; start <= len(input); {
+		end := start + indexSeparator(input[start:])
+
+		_, sipURI, _, err := ParseNameAddr(input[start:end])
+		if err != nil {
+			var malformed MalformedURIError
+			if errors.As(err, &malformed) && malformed.Offset != 0 {
+				malformed.Offset += start
+				err = malformed
+			}
+
+			return nil, fmt.Errorf("sip: element %d: %w", len(uris), err)
+		}
+
+		uris = append(uris, sipURI)
+		start = end + 1
+	}
+
+	return uris, nil
+}
+
+// indexSeparator returns the index of the first comma outside of any angle
+// brackets or quoted-string, or the length of the input if there is none.
+func indexSeparator(input string) int {
+	var inAngle, inQuote bool
+
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case inQuote && c == '\\': // §25.1 quoted-pair
+			i++
+		case c == '"' && !inAngle:
+			inQuote = !inQuote
+		case inQuote:
+		case c == '<':
+			inAngle = true
+		case c == '>':
+			inAngle = false
+		case c == ',' && !inAngle:
+			return i
+		}
+	}
+
+	return len(input)
+}
+
+// unquote reads the quoted-string at the start of the input, returning its
+// unescaped contents and the remaining input.
+func unquote(input string) (string, string, bool) {
+	var builder strings.Builder
+
+	for i := 1; i < len(input); i++ {
+		switch c := input[i]; c {
+		case '"':
+			return builder.String(), input[i+1:], true
+		case '\\': // §25.1 quoted-pair
+			if i+1 == len(input) {
+				return "", "", false
+			}
+
+			i++
+			builder.WriteByte(input[i])
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	return "", "", false
+}
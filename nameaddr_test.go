@@ -0,0 +1,129 @@
+package sipuri_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestParseNameAddr(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		input   string
+		display string
+		uri     string
+		params  sipuri.KeyValuePairs
+		msg     string
+	}
+
+	tests := []test{
+		{"<sip:alice@atlanta.com;transport=tcp>;expires=3600", "", "sip:alice@atlanta.com;transport=tcp", sipuri.KeyValuePairs{"expires": {"3600"}}, "angle brackets"},
+		{`"Alice" <sip:alice@atlanta.com>`, "Alice", "sip:alice@atlanta.com", nil, "quoted display name"},
+		{`"Alice \"Al\" <Smith>" <sip:alice@atlanta.com>;tag=1928301774`, `Alice "Al" <Smith>`, "sip:alice@atlanta.com", sipuri.KeyValuePairs{"tag": {"1928301774"}}, "escaped quoted display name"},
+		{"Bob <sips:bob@biloxi.com> ;tag=a6c85cf", "Bob", "sips:bob@biloxi.com", sipuri.KeyValuePairs{"tag": {"a6c85cf"}}, "token display name"},
+		{"The Operator <sip:operator@cs.columbia.edu>", "The Operator", "sip:operator@cs.columbia.edu", nil, "multiple token display name"},
+		{"sip:carol@chicago.com;tag=887s", "", "sip:carol@chicago.com", sipuri.KeyValuePairs{"tag": {"887s"}}, "bare uri params are header params"},
+		{"  sip:carol@chicago.com  ", "", "sip:carol@chicago.com", nil, "bare uri with whitespace"},
+		{"<sip:alice@atlanta.com>;lr;q=0.7", "", "sip:alice@atlanta.com", sipuri.KeyValuePairs{"lr": {}, "q": {"0.7"}}, "flag header param"},
+	}
+
+	for _, test := range tests {
+		display, uri, params, err := sipuri.ParseNameAddr(test.input)
+		if err != nil {
+			t.Fatalf("unexpected error %q in %s", err, test.msg)
+		}
+
+		equalF(t, test.display, display, "display name mismatch in %s", test.msg)
+		equalF(t, test.uri, uri.String(), "uri mismatch in %s", test.msg)
+		equalF(t, test.params, params, "header params mismatch in %s", test.msg)
+	}
+}
+
+func TestParseNameAddrError(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		input string
+		err   error
+		msg   string
+	}
+
+	tests := []test{
+		{`"Alice <sip:alice@atlanta.com>`, sipuri.ErrMalformedNameAddr, "unterminated quote"},
+		{`"Alice" sip:alice@atlanta.com`, sipuri.ErrMalformedNameAddr, "quoted name without brackets"},
+		{"<sip:alice@atlanta.com", sipuri.ErrMalformedNameAddr, "unterminated bracket"},
+		{"<sip:alice@atlanta.com>expires=1", sipuri.ErrMalformedNameAddr, "junk after bracket"},
+		{"<http://atlanta.com>", sipuri.ErrInvalidScheme, "invalid scheme"},
+		{"<sip:@atlanta.com>", sipuri.MalformedURIError{Cause: sipuri.MissingUser}, "malformed uri"},
+		{"<sip:alice@atlanta.com>;tag=%xx", sipuri.MalformedURIError{Cause: sipuri.MalformedParams}, "malformed header params"},
+	}
+
+	for _, test := range tests {
+		_, uri, _, err := sipuri.ParseNameAddr(test.input)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("expected error %q but got %q in %s", test.err, err, test.msg)
+		}
+
+		equalF(t, (*sipuri.URI)(nil), uri, "nil received %s", test.msg)
+	}
+
+	_, _, _, err := sipuri.ParseNameAddr(`"Bob" <sip:bob@biloxi.com;%xx>`)
+	equalF(t, `sip: malformed uri: malformed params: sip: invalid URL escape "%xx"`, err.Error(), "uri offset relative to input")
+	offsetF(t, 26, err, "uri offset relative to input")
+
+	_, _, _, err = sipuri.ParseNameAddr(`<sip:bob@biloxi.com>;tag=%xx`)
+	equalF(t, `sip: malformed uri: malformed params: sip: invalid URL escape "%xx"`, err.Error(), "param offset relative to input")
+	offsetF(t, 25, err, "param offset relative to input")
+}
+
+func TestParseMany(t *testing.T) {
+	t.Parallel()
+
+	uris, err := sipuri.ParseMany(`<sip:p1.example.com;lr>, "Proxy, \"Two\"" <sip:p2.example.com;lr;x=a,b>;rr=1`)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, 2, len(uris), "elements")
+	equalF(t, "sip:p1.example.com;lr", uris[0].String(), "first element")
+	equalF(t, "a,b", uris[1].Params().Get("x"), "comma within brackets")
+
+	uris, err = sipuri.ParseMany("sip:alice@atlanta.com")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "sip:alice@atlanta.com", uris[0].String(), "single bare element")
+
+	_, err = sipuri.ParseMany("<sip:p1.example.com;lr>,<sip:p2.example.com;%xx>")
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedParams}) {
+		t.Fatalf("expected malformed params error but got %q", err)
+	}
+
+	equalF(t, `sip: element 1: sip: malformed uri: malformed params: sip: invalid URL escape "%xx"`, err.Error(), "element and offset")
+	offsetF(t, 44, err, "element and offset")
+
+	_, err = sipuri.ParseMany("<sip:p1.example.com;lr>,")
+	if !errors.Is(err, sipuri.ErrInvalidScheme) {
+		t.Fatalf("expected invalid scheme error but got %q", err)
+	}
+}
+
+func TestIsWildcard(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"*", " * ", "\t*"} {
+		equalF(t, true, sipuri.IsWildcard(input), "wildcard %q", input)
+
+		_, err := sipuri.Parse(input)
+		if !errors.Is(err, sipuri.ErrInvalidScheme) {
+			t.Fatalf("expected invalid scheme error but got %q", err)
+		}
+	}
+
+	for _, input := range []string{"", "**", "<*>", "sip:*@atlanta.com", "<sip:alice@atlanta.com>"} {
+		equalF(t, false, sipuri.IsWildcard(input), "not wildcard %q", input)
+	}
+}
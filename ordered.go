@@ -0,0 +1,267 @@
+package sipuri
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// Pair is a single entry of an [OrderedPairs] store.
+type Pair struct {
+	Key   string
+	Value string
+	// Flag marks a key without a value, such as the lr param, which is
+	// encoded without an '='.
+	Flag bool
+}
+
+// OrderedPairs stores key-value pairs in the order they were decoded or added
+// and implements [KeyValueStore]. Unlike [KeyValuePairs] the encoded form
+// keeps that order rather than sorting by key.
+type OrderedPairs []Pair
+
+// Decode populates the store with the given data, returing any encoding errors
+// encountered.
+func (p *OrderedPairs) Decode(input, separator string) error {
+	var err error
+	*p, _, err = decodeOrdered(input, separator)
+
+	return err
+}
+
+// DecodeURLValuesOrdered decodes the input like [DecodeURLValues] but into
+// an [OrderedPairs] store, keeping the order of the pairs including any
+// repeated keys. A key without an '=' is decoded as a flag.
+func DecodeURLValuesOrdered(input, separator string) (OrderedPairs, error) {
+	result, _, err := decodeOrdered(input, separator)
+
+	return result, err
+}
+
+// decodeOrdered decodes the pairs in order, returning the index of the
+// malformed escape on error.
+func decodeOrdered(input, separator string) (OrderedPairs, int, error) {
+	pairs := strings.Split(input, separator)
+	result := make(OrderedPairs, 0, len(pairs))
+	offset := 0
+
+	for _, pair := range pairs {
+		// An empty pair has nothing to store, as with DecodeURLValues.
+		if pair == "" {
+			offset += len(separator)
+
+			continue
+		}
+
+		rawKey, rawValue, hasValue := strings.Cut(pair, "=")
+
+		key, pos, err := unescape(rawKey)
+		if err != nil {
+			return nil, offset + pos, err
+		}
+
+		value, pos, err := unescape(rawValue)
+		if err != nil {
+			return nil, offset + len(rawKey) + 1 + pos, err
+		}
+
+		result = append(result, Pair{Key: key, Value: value, Flag: !hasValue})
+		offset += len(pair) + len(separator)
+	}
+
+	return result, 0, nil
+}
+
+// Get returns the first value for the given key. Empty string otherwise.
+func (p OrderedPairs) Get(key string) string {
+	for _, pair := range p {
+		if pair.Key == key {
+			return pair.Value
+		}
+	}
+
+	return ""
+}
+
+// GetFold returns the first value for the given key matched
+// case-insensitively, as with header names. Empty string otherwise.
+func (p OrderedPairs) GetFold(key string) string {
+	for _, pair := range p {
+		if strings.EqualFold(pair.Key, key) {
+			return pair.Value
+		}
+	}
+
+	return ""
+}
+
+// Keys returns the distinct keys in the order they first appear.
+func (p OrderedPairs) Keys() []string {
+	var keys []string
+
+	for i, pair := range p {
+		if p[:i].index(pair.Key) < 0 {
+			keys = append(keys, pair.Key)
+		}
+	}
+
+	return keys
+}
+
+// SortedKeys returns the distinct keys sorted, rather than in the order they
+// first appear.
+func (p OrderedPairs) SortedKeys() []string {
+	keys := p.Keys()
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Values returns all the values for the given key in order. A flag has no
+// values.
+func (p OrderedPairs) Values(key string) []string {
+	var values []string
+
+	for _, pair := range p {
+		if pair.Key == key && !pair.Flag {
+			values = append(values, pair.Value)
+		}
+	}
+
+	return values
+}
+
+// ForEach calls fn for each pair in order, stopping early if fn returns
+// false. A flag is passed with an empty value.
+func (p OrderedPairs) ForEach(fn func(key, value string) bool) {
+	for _, pair := range p {
+		if !fn(pair.Key, pair.Value) {
+			return
+		}
+	}
+}
+
+// Set sets the key to value. The first pair with the key is replaced in place
+// and any others removed, otherwise the pair is appended.
+func (p *OrderedPairs) Set(key, value string) {
+	result := (*p)[:0]
+	found := false
+
+	for _, pair := range *p {
+		if pair.Key != key {
+			result = append(result, pair)
+		} else if !found {
+			result = append(result, Pair{Key: key, Value: value})
+			found = true
+		}
+	}
+
+	if !found {
+		result = append(result, Pair{Key: key, Value: value})
+	}
+
+	*p = result
+}
+
+// Add appends the key-value pair.
+func (p *OrderedPairs) Add(key, value string) {
+	*p = append(*p, Pair{Key: key, Value: value})
+}
+
+// Del deletes all pairs with the key.
+func (p *OrderedPairs) Del(key string) {
+	result := (*p)[:0]
+
+	for _, pair := range *p {
+		if pair.Key != key {
+			result = append(result, pair)
+		}
+	}
+
+	*p = result
+}
+
+// Encode stringifies the pairs in order, url encoding keys and values
+// joining with an ampersand.
+func (p OrderedPairs) Encode() string {
+	return encodeOrdered(p, "&")
+}
+
+// EncodeSep is like [OrderedPairs.Encode] but joins the pairs in order with
+// the given separator, such as ';' for params.
+func (p OrderedPairs) EncodeSep(separator string) string {
+	return encodeOrdered(p, separator)
+}
+
+// WriteEncoded writes the encoding of [OrderedPairs.Encode] to w, joining the
+// pairs in order with the given separator.
+func (p OrderedPairs) WriteEncoded(w io.Writer, separator string) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	return io.WriteString(w, encodeOrdered(p, separator)) //nolint:wrapcheck
+}
+
+// Len returns the number of distinct keys.
+func (p OrderedPairs) Len() int {
+	var count int
+
+	for i, pair := range p {
+		if p[:i].index(pair.Key) < 0 {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Empty returns if the store contains no keys.
+func (p OrderedPairs) Empty() bool {
+	return len(p) == 0
+}
+
+// Clone returns a copy of the pairs.
+func (p OrderedPairs) Clone() OrderedPairs {
+	if p == nil {
+		return nil
+	}
+
+	return append(make(OrderedPairs, 0, len(p)), p...)
+}
+
+// index returns the position of the first pair with the key, or -1.
+func (p OrderedPairs) index(key string) int {
+	for i, pair := range p {
+		if pair.Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// encodeOrdered encodes the pairs joining them with the separator.
+func encodeOrdered(pairs OrderedPairs, separator string) string {
+	var builder strings.Builder
+
+	for _, pair := range pairs {
+		// An empty flag has nothing to write.
+		if pair.Key == "" && pair.Flag {
+			continue
+		}
+
+		if builder.Len() > 0 {
+			builder.WriteString(separator)
+		}
+
+		builder.WriteString(escape(pair.Key, encodeQueryComponent))
+
+		if !pair.Flag {
+			builder.WriteByte('=')
+			builder.WriteString(escape(pair.Value, encodeQueryComponent))
+		}
+	}
+
+	return builder.String()
+}
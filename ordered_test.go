@@ -0,0 +1,149 @@
+package sipuri_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestOrderedPairs(t *testing.T) {
+	t.Parallel()
+
+	var pairs sipuri.OrderedPairs
+	if err := (&pairs).Decode("b=1;a=2;lr;b=3;c=", ";"); err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, sipuri.OrderedPairs{
+		{Key: "b", Value: "1"},
+		{Key: "a", Value: "2"},
+		{Key: "lr", Flag: true},
+		{Key: "b", Value: "3"},
+		{Key: "c"},
+	}, pairs, "decoded pairs")
+
+	equalF(t, "1", pairs.Get("b"), "first value returned")
+	equalF(t, "", pairs.Get("lr"), "flag has no value")
+	equalF(t, "", pairs.Get("missing"), "missing key")
+	equalF(t, 4, pairs.Len(), "distinct keys")
+	equalF(t, false, pairs.Empty(), "not empty")
+	equalF(t, "b=1&a=2&lr&b=3&c=", pairs.Encode(), "encoded in order")
+	equalF(t, []string{"b", "a", "lr", "c"}, pairs.Keys(), "keys in order")
+	equalF(t, []string{"a", "b", "c", "lr"}, pairs.SortedKeys(), "sorted keys")
+	equalF(t, []string{"1", "3"}, pairs.Values("b"), "all values in order")
+	equalF(t, []string(nil), pairs.Values("lr"), "flag has no values")
+
+	pairs.Set("b", "4")
+	equalF(t, "b=4&a=2&lr&c=", pairs.Encode(), "set replaces first and removes others")
+
+	pairs.Add("d", "x y")
+	equalF(t, "b=4&a=2&lr&c=&d=x%20y", pairs.Encode(), "add appends")
+
+	pairs.Del("a")
+	equalF(t, "b=4&lr&c=&d=x%20y", pairs.Encode(), "del removes key")
+
+	pairs.Set("e", "5")
+	equalF(t, "b=4&lr&c=&d=x%20y&e=5", pairs.Encode(), "set appends missing key")
+
+	equalF(t, true, sipuri.OrderedPairs{}.Empty(), "empty")
+}
+
+func TestDecodeURLValuesOrdered(t *testing.T) {
+	t.Parallel()
+
+	pairs, err := sipuri.DecodeURLValuesOrdered("b=1&a=x%20y&lr&b=2&c=", "&")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, sipuri.OrderedPairs{
+		{Key: "b", Value: "1"},
+		{Key: "a", Value: "x y"},
+		{Key: "lr", Flag: true},
+		{Key: "b", Value: "2"},
+		{Key: "c"},
+	}, pairs, "decoded in order")
+
+	unordered, err := sipuri.DecodeURLValues("b=1&a=x%20y&lr&b=2&c=", "&")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	merged := sipuri.KeyValuePairs{}
+	merged.MergeAdd(pairs)
+	equalF(t, unordered, merged, "same contents as unordered decoder")
+
+	_, err = sipuri.DecodeURLValuesOrdered("a=%xx", ";")
+	if !errors.Is(err, sipuri.EscapeError("")) {
+		t.Fatalf("expected escape error but got %q", err)
+	}
+}
+
+func TestParseOrderedParams(t *testing.T) {
+	t.Parallel()
+
+	for _, lazy := range []bool{false, true} {
+		parse := sipuri.ParseWithOptions
+		if lazy {
+			parse = lazily(parse, sipuri.WithLazy())
+		}
+
+		sipURI, err := parse("sip:alice@atlanta.com;b=1;a=2;lr", sipuri.WithOrderedParams())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "b=1&a=2&lr", sipURI.Params().Encode(), "params in input order")
+		equalF(t, "b=1;a=2;lr", sipURI.Params().EncodeSep(";"), "params joined with ; in input order")
+		equalF(t, true, sipURI.LooseRouting(), "flag detected")
+
+		modified := sipURI.WithParam("transport", "tcp")
+
+		equalF(t, "b=1&a=2&lr&transport=tcp", modified.Params().Encode(), "added param keeps order")
+		equalF(t, "b=1&a=2&lr", sipURI.Params().Encode(), "original params modified")
+
+		clone := sipURI.Clone()
+		clone.Params().(sipuri.OrderedPairs)[0].Value = "9" //nolint:forcetypeassert
+
+		equalF(t, "1", sipURI.Params().Get("b"), "original params modified by clone")
+	}
+}
+
+func TestParseOrderedHeaders(t *testing.T) {
+	t.Parallel()
+
+	for _, lazy := range []bool{false, true} {
+		parse := sipuri.ParseWithOptions
+		if lazy {
+			parse = lazily(parse, sipuri.WithLazy())
+		}
+
+		sipURI, err := parse("sip:alice@atlanta.com;b=1;a=2?subject=x&priority=1", sipuri.WithOrderedHeaders())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "subject=x&priority=1", sipURI.Headers().Encode(), "headers in input order")
+
+		modified := sipURI.WithHeader("to", "bob")
+		equalF(t, "subject=x&priority=1&to=bob", modified.Headers().Encode(), "added header keeps order")
+
+		_, isOrdered := sipURI.Params().(sipuri.OrderedPairs)
+		equalF(t, false, isOrdered, "params not ordered")
+
+		sipURI, err = parse("sip:alice@atlanta.com?subject=x&priority=1", sipuri.WithOrderedHeaders())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "sip:alice@atlanta.com?subject=x&priority=1", sipURI.String(), "string keeps header order")
+
+		sipURI, err = parse("sip:alice@atlanta.com?subject=x;priority=1", sipuri.WithOrderedHeaders(), sipuri.WithHeaderSeparator(";"))
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "sip:alice@atlanta.com?subject=x&priority=1", sipURI.String(), "custom separator")
+	}
+}
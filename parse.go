@@ -1,126 +1,485 @@
 package sipuri
 
-import "strings"
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unsafe"
+)
+
+// parser holds the options used while parsing a URI.
+type parser struct {
+	aliased        bool
+	controlChars   bool
+	headerSep      string
+	lazy           bool
+	maxLength      int
+	noPassword     bool
+	orderedHeaders bool
+	orderedParams  bool
+	phoneUser      bool
+	pooled         bool
+	raw            bool
+	singleValued   bool
+	strictHost     bool
+	transport      bool
+	trimSpace      bool
+	utf8           bool
+}
+
+type parseOption func(p *parser)
+
+// WithOrderedParams decodes the params into an [OrderedPairs] store so the
+// order of the input is preserved. The params are decoded eagerly even when
+// parsed lazily.
+func WithOrderedParams() parseOption {
+	return func(p *parser) {
+		p.orderedParams = true
+	}
+}
+
+// WithLazy lazily loads the params & headers like [ParseLazy].
+func WithLazy() parseOption {
+	return func(p *parser) {
+		p.lazy = true
+	}
+}
+
+// WithHeaderSeparator splits the headers on the given separator rather than
+// the '&' of §19.1.1, to accept the headers of non-conformant devices such as
+// ?a=1;b=2. The headers are still joined with '&' by [URI.String].
+func WithHeaderSeparator(sep string) parseOption {
+	return func(p *parser) {
+		p.headerSep = sep
+	}
+}
+
+// WithTrimSpace ignores any leading and trailing whitespace of the input, such
+// as " sip:alice@atlanta.com\t". Whitespace within the URI is parsed as
+// before. The offset of a [MalformedURIError] is relative to the untrimmed
+// input.
+func WithTrimSpace() parseOption {
+	return func(p *parser) {
+		p.trimSpace = true
+	}
+}
+
+// WithOrderedHeaders decodes the headers into an [OrderedPairs] store so the
+// order of the input is preserved, independently of [WithOrderedParams]. The
+// headers are decoded eagerly even when parsed lazily.
+func WithOrderedHeaders() parseOption {
+	return func(p *parser) {
+		p.orderedHeaders = true
+	}
+}
+
+// WithRaw keeps the input so [URI.String] returns it byte for byte, such as
+// the case of any percent-encoding, rather than rebuilding it from the decoded
+// components. This is useful when a signature is computed over the URI.
+//
+// The input is discarded by the modifying methods, such as [URI.WithParam],
+// whose result is rebuilt as normal.
+func WithRaw() parseOption {
+	return func(p *parser) {
+		p.raw = true
+	}
+}
+
+// WithAliasedBytes has [ParseBytes] parse the slice in place rather than
+// copying it. The components of the returned URI, including the keys of the
+// params and headers maps, then alias the slice so it must not be modified
+// while the URI is in use. It has no effect on parsing a string.
+func WithAliasedBytes() parseOption {
+	return func(p *parser) {
+		p.aliased = true
+	}
+}
+
+// WithMaxLength rejects an input longer than n bytes with a [MalformedURIError]
+// of the [TooLong] cause, before any of it is decoded, guarding against
+// pathological untrusted input. By default, or with a limit of zero or less,
+// the length is unlimited.
+func WithMaxLength(n int) parseOption {
+	return func(p *parser) {
+		p.maxLength = n
+	}
+}
 
 // Parse parses the given uri.
+//
+// The scheme is matched case-insensitively as per §19.1.1. Any other scheme
+// returns [ErrInvalidScheme], as does the "*" of a wildcard Contact which can
+// be checked for with [IsWildcard].
 func Parse(uri string) (*URI, error) {
-	if strings.HasPrefix(uri, SIPProtocol) {
-		return parse(SIP, uri[len(SIPProtocol):], false)
+	return pointer(ParseValue(uri))
+}
+
+// ParseWithOptions parses the given uri like [Parse] applying the given
+// options, such as [WithOrderedParams] or [WithMaxLength].
+func ParseWithOptions(uri string, opts ...parseOption) (*URI, error) {
+	return pointer(ParseValue(uri, opts...))
+}
+
+// ParseValue parses the given uri like [Parse] but returns the URI by value,
+// avoiding allocating the URI on the heap.
+func ParseValue(uri string, opts ...parseOption) (URI, error) {
+	return newParser(opts).parse(uri)
+}
+
+// ParseWithScheme parses the given uri like [Parse] but also returns the
+// scheme exactly as it appeared in the input, without the colon, such as
+// "SIP" for SIP:alice@atlanta.com where [URI.Scheme] returns "sip".
+func ParseWithScheme(uri string, opts ...parseOption) (*URI, string, error) {
+	conf := newParser(opts)
+
+	sipURI, err := conf.parse(uri)
+	if err != nil {
+		return nil, "", err
 	}
 
-	if strings.HasPrefix(uri, SIPSProtocol) {
-		return parse(SIPS, uri[len(SIPSProtocol):], false)
+	if conf.trimSpace {
+		uri = strings.TrimLeftFunc(uri, unicode.IsSpace)
 	}
 
-	return nil, ErrInvalidScheme
+	return &sipURI, uri[:len(sipURI.Scheme())], nil
+}
+
+// pointer returns a pointer to the URI unless there is an error.
+func pointer(sipURI URI, err error) (*URI, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return &sipURI, nil
+}
+
+// ParseStrict parses the given uri applying all the validation options, such
+// as [WithStrictHost], [WithPhoneUserValidation] and [WithUTF8Validation].
+func ParseStrict(uri string, opts ...parseOption) (*URI, error) {
+	return ParseWithOptions(uri, append(strictOptions(), opts...)...)
+}
+
+// ParseBytes parses the given uri from a byte slice.
+//
+// The input is copied once, with all components of the returned URI sharing
+// that copy, so the slice may be reused or modified after the call returns.
+// See [WithAliasedBytes] to avoid the copy.
+func ParseBytes(uri []byte, opts ...parseOption) (*URI, error) {
+	conf := newParser(opts)
+	if conf.aliased {
+		return pointer(conf.parse(bytesToString(uri)))
+	}
+
+	return pointer(conf.parse(string(uri)))
+}
+
+// bytesToString returns the bytes as a string sharing the same memory.
+func bytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b)) //nolint:gosec
 }
 
 // ParseLazy parses the given uri, lazily loading the uri parameters & headers.
 func ParseLazy(uri string) (*URI, error) {
-	if strings.HasPrefix(uri, SIPProtocol) {
-		return parse(SIP, uri[len(SIPProtocol):], true)
+	return ParseWithOptions(uri, WithLazy())
+}
+
+// Valid reports whether [Parse] would parse the given uri without error. It
+// runs the same structural checks but does not decode the components into a
+// URI, so does not allocate unless the host is escaped.
+func Valid(uri string) bool {
+	switch {
+	case hasScheme(uri, SIPProtocol):
+		uri = uri[len(SIPProtocol):]
+	case hasScheme(uri, SIPSProtocol):
+		uri = uri[len(SIPSProtocol):]
+	default:
+		return false
 	}
 
-	if strings.HasPrefix(uri, SIPSProtocol) {
-		return parse(SIPS, uri[len(SIPSProtocol):], true)
+	userinfo, postfix, hasAt := strings.Cut(uri, "@")
+
+	if hasAt {
+		if userinfo == "" || userinfo[0] == ':' {
+			return false
+		}
+	} else {
+		userinfo, postfix = postfix, userinfo
+	}
+
+	prefix, headers, _ := strings.Cut(postfix, "?")
+	host, params, _ := strings.Cut(prefix, ";")
+
+	if host == "" {
+		return false
+	}
+
+	if _, err := checkEscapes(userinfo); err != nil {
+		return false
 	}
 
-	return nil, ErrInvalidScheme
+	host, err := Unescape(host)
+	if err != nil {
+		return false
+	}
+
+	if _, _, err := splitHostPort(host); err != nil {
+		return false
+	}
+
+	if _, err := checkEscapes(params); err != nil {
+		return false
+	}
+
+	_, err = checkEscapes(headers)
+
+	return err == nil
 }
 
+func newParser(opts []parseOption) parser {
+	var conf parser
+
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	return conf
+}
+
+// parse matches the scheme before parsing the rest of the uri.
+func (conf parser) parse(uri string) (URI, error) {
+	if conf.maxLength > 0 && len(uri) > conf.maxLength {
+		return URI{}, MalformedURIError{Cause: TooLong, Offset: conf.maxLength}
+	}
+
+	if conf.trimSpace {
+		return conf.parseTrimmed(uri)
+	}
+
+	if hasScheme(uri, SIPProtocol) {
+		return parse(SIP, uri, len(SIPProtocol), conf)
+	}
+
+	if hasScheme(uri, SIPSProtocol) {
+		return parse(SIPS, uri, len(SIPSProtocol), conf)
+	}
+
+	return URI{}, ErrInvalidScheme
+}
+
+// parseTrimmed parses the uri without its leading and trailing whitespace.
+func (conf parser) parseTrimmed(uri string) (URI, error) {
+	trimmed := strings.TrimLeftFunc(uri, unicode.IsSpace)
+	lead := len(uri) - len(trimmed)
+
+	conf.trimSpace = false
+
+	sipURI, err := conf.parse(strings.TrimRightFunc(trimmed, unicode.IsSpace))
+	if err != nil {
+		var malformed MalformedURIError
+		if errors.As(err, &malformed) && malformed.Offset != 0 {
+			malformed.Offset += lead
+
+			return URI{}, malformed
+		}
+
+		return URI{}, err
+	}
+
+	return sipURI, nil
+}
+
+// hasScheme reports if the uri begins with the scheme ignoring case.
+func hasScheme(uri, scheme string) bool {
+	return len(uri) >= len(scheme) && strings.EqualFold(uri[:len(scheme)], scheme)
+}
+
+// parse parses the uri following the scheme, which ends at index start.
+//
 //nolint:cyclop,funlen
-func parse(proto Protocol, uri string, lazy bool) (*URI, error) {
+func parse(proto Protocol, uri string, start int, conf parser) (URI, error) {
 	sipURI := URI{proto: proto}
 
+	if conf.raw {
+		sipURI.raw = uri
+	}
+
+	sipURI.pooled = conf.pooled
+
+	uri = uri[start:]
+
 	// @ in the set of reserved chars of the user portion. Therefore the first
 	userinfo, postfix, hasAt := strings.Cut(uri, "@") // @ must be encoded in the host and pass
 
 	if hasAt {
 		// §19.1.1 "If the @ sign is present in a SIP or SIPS URI, the user field MUST NOT be empty."
 		if userinfo == "" {
-			return nil, MalformedURIError{Cause: MissingUser}
+			return URI{}, MalformedURIError{Cause: MissingUser, Offset: start}
 		}
 	} else {
 		userinfo, postfix = postfix, userinfo // swap (makes userinfo empty)
 	}
 
+	// The index of each component in the input.
+	at := componentOffsets{user: start, host: start + len(uri) - len(postfix)}
+
 	// The uri must have been a single '@'
 	if postfix == "" {
-		return nil, MalformedURIError{Cause: MissingHost}
+		return URI{}, MalformedURIError{Cause: MissingHost, Offset: at.host}
 	}
 
 	prefix, headers, hadHeader := strings.Cut(postfix, "?")
 	host, params, hadParam := strings.Cut(prefix, ";")
 
+	at.params = at.host + len(host) + 1
+	at.headers = at.host + len(prefix) + 1
+
 	// §19.1.2 host mandatory in all contexts
 	if host == "" {
-		return nil, MalformedURIError{Cause: MissingHost}
+		return URI{}, MalformedURIError{Cause: MissingHost, Offset: at.host}
 	}
 
 	sipURI.hadHeader = hadHeader
 	sipURI.hadParam = hadParam
 
 	// RFC requires : to be escaped in the userinfo. So split on :.
-	sipURI.user, sipURI.pass, sipURI.hadPass = strings.Cut(userinfo, ":")
+	user, pass, hadPass := strings.Cut(userinfo, ":")
+
+	// §25.1 userinfo = ( user / telephone-subscriber ) [ ":" password ] "@"
+	if hasAt && user == "" {
+		return URI{}, MalformedURIError{Cause: MissingUser, Offset: at.user}
+	}
 
-	user, err := Unescape(sipURI.user)
+	var (
+		pos int
+		err error
+	)
+
+	sipURI.rawUser, sipURI.rawHost = user, host
+
+	sipURI.user, pos, err = unescape(user)
+	if err != nil {
+		return URI{}, MalformedURIError{Cause: MalformedUser, Err: err, Offset: at.user + pos}
+	}
+
+	sipURI.pass, pos, err = unescape(pass)
 	if err != nil {
-		return nil, MalformedURIError{Cause: MalformedUser, Err: err}
+		return URI{}, MalformedURIError{Cause: MalformedUser, Err: err, Offset: at.user + len(user) + 1 + pos}
 	}
 
-	sipURI.user = user
+	sipURI.hadPass = hadPass
 
 	// Typically the host should not contain any escaped characters but
 	// it is possible in the spec.
-	host, err = Unescape(host)
+	host, pos, err = unescape(host)
 	if err != nil {
-		return nil, MalformedURIError{Cause: MalformedHost, Err: err}
+		return URI{}, MalformedURIError{Cause: MalformedHost, Err: err, Offset: at.host + pos}
 	}
 
 	sipURI.host = host
 
 	// Check the host port is not malformed
-	if _, _, err := sipURI.SplitHostPort(); err != nil {
-		return nil, MalformedURIError{Cause: MalformedHost, Err: err}
+	if err := sipURI.splitHost(); err != nil {
+		return URI{}, at.locate(err)
 	}
 
-	switch {
-	case params == "":
+	if params == "" {
 		sipURI.params = EmptyStore{}
-	case lazy:
-		var temp LazyStore
-		if err := (&temp).Decode(params, ";"); err != nil {
-			return nil, MalformedURIError{Cause: MalformedParams, Err: err}
+	} else {
+		sipURI.params, pos, err = conf.decodeStore(params, ";", conf.orderedParams)
+		if err != nil {
+			return URI{}, MalformedURIError{Cause: MalformedParams, Err: err, Offset: at.params + pos}
 		}
 
-		sipURI.params = &temp
-	default:
-		var temp KeyValuePairs
-		if err := (&temp).Decode(params, ";"); err != nil {
-			return nil, MalformedURIError{Cause: MalformedParams, Err: err}
+		if conf.singleValued {
+			if pos, repeated := repeatedParam(params); repeated {
+				ReleaseURI(&sipURI)
+
+				return URI{}, MalformedURIError{Cause: MalformedParams, Offset: at.params + pos}
+			}
 		}
+	}
 
-		sipURI.params = temp
+	if headers == "" {
+		sipURI.headers = EmptyStore{}
+	} else {
+		sipURI.headers, pos, err = conf.decodeStore(headers, conf.headerSeparator(), conf.orderedHeaders)
+		if err != nil {
+			ReleaseURI(&sipURI)
+
+			return URI{}, MalformedURIError{Cause: MalformedHeaders, Err: err, Offset: at.headers + pos}
+		}
 	}
 
+	if err := conf.validate(sipURI); err != nil {
+		ReleaseURI(&sipURI)
+
+		return URI{}, at.locate(err)
+	}
+
+	return sipURI, nil
+}
+
+// headerSeparator returns the separator the headers are split on.
+func (conf parser) headerSeparator() string {
+	if conf.headerSep == "" {
+		return "&"
+	}
+
+	return conf.headerSep
+}
+
+// decodeStore decodes the params or headers into the store chosen by the
+// options. On error the index of the malformed escape is also returned.
+func (conf parser) decodeStore(input, separator string, ordered bool) (KeyValueStore, int, error) {
 	switch {
-	case headers == "":
-		sipURI.headers = EmptyStore{}
-	case lazy:
-		var temp LazyStore
-		if err := (&temp).Decode(headers, "&"); err != nil {
-			return nil, MalformedURIError{Cause: MalformedHeaders, Err: err}
+	case ordered:
+		return decodeOrdered(input, separator)
+	case conf.lazy:
+		if pos, err := checkEscapes(input); err != nil {
+			return nil, pos, err
 		}
 
-		sipURI.headers = &temp
-	default:
-		var temp KeyValuePairs
-		if err := (&temp).Decode(headers, "&"); err != nil {
-			return nil, MalformedURIError{Cause: MalformedHeaders, Err: err}
+		return &LazyStore{input: input, separator: separator}, 0, nil
+	case conf.pooled:
+		pairs := pooledPairs()
+
+		store, pos, err := decodeURLValuesInto(pairs, input, separator)
+		if err != nil {
+			releasePairs(pairs)
 		}
 
-		sipURI.headers = temp
+		return store, pos, err
+	default:
+		return decodeURLValues(input, separator)
 	}
+}
 
-	return &sipURI, nil
+// componentOffsets holds the index in the input each component starts at.
+type componentOffsets struct {
+	user    int
+	host    int
+	params  int
+	headers int
+}
+
+// locate sets the offset of a [MalformedURIError] without one to the start of
+// the component matching its cause.
+func (at componentOffsets) locate(err error) error {
+	malformed, ok := err.(MalformedURIError) //nolint:errorlint
+	if !ok || malformed.Offset != 0 {
+		return err
+	}
+
+	switch malformed.Cause { //nolint:exhaustive
+	case MissingUser, MalformedUser, DeprecatedPassword:
+		malformed.Offset = at.user
+	case MissingHost, MalformedHost:
+		malformed.Offset = at.host
+	case MalformedParams, TransportSchemeMismatch:
+		malformed.Offset = at.params
+	case MalformedHeaders:
+		malformed.Offset = at.headers
+	}
+
+	return malformed
 }
@@ -4,15 +4,24 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/percivalalb/sipuri"
 )
 
 //nolint:gochecknoglobals
-var parseFuncs = [2](func(string) (*sipuri.URI, error)){
+var parseFuncs = [3](func(string) (*sipuri.URI, error)){
 	sipuri.Parse,
 	sipuri.ParseLazy,
+	func(uri string) (*sipuri.URI, error) {
+		sipURI, err := sipuri.ParseValue(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		return &sipURI, nil
+	},
 }
 
 func TestParse(t *testing.T) {
@@ -38,6 +47,25 @@ func TestParse(t *testing.T) {
 			}),
 		), "UDP", "template uri"},
 
+		{"sip:user:password@host:port;uri-parameters?headers", sipuri.New(
+			"user",
+			"host:port",
+			sipuri.WithPassword("password"),
+			sipuri.WithParams(sipuri.KeyValuePairs{
+				"uri-parameters": {},
+			}),
+			sipuri.WithHeaders(sipuri.KeyValuePairs{
+				"headers": {},
+			}),
+		), "UDP", "template uri without values"},
+		{"sip:p1.example.com;lr", sipuri.New(
+			"",
+			"p1.example.com",
+			sipuri.WithParams(sipuri.KeyValuePairs{
+				"lr": {},
+			}),
+		), "UDP", "valueless lr param"},
+
 		// From https://www.rfc-editor.org/rfc/rfc3261#section-19.1.1
 		{"sip:alice@atlanta.com", sipuri.New(
 			"alice",
@@ -156,6 +184,414 @@ func TestParse(t *testing.T) {
 
 			equalF(t, test.transp, sipURI.Transport(), "determining transport protocol %s", test.msg)
 		}
+
+		equalF(t, true, sipuri.Valid(test.uri), "valid in %s", test.msg)
+	}
+}
+
+func TestParseSchemeCase(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		proto sipuri.Protocol
+		canon string
+		msg   string
+	}
+
+	tests := []test{
+		{"SIP:alice@atlanta.com", sipuri.SIP, "sip:alice@atlanta.com", "uppercase sip"},
+		{"SIPS:alice@atlanta.com", sipuri.SIPS, "sips:alice@atlanta.com", "uppercase sips"},
+		{"SiP:alice@atlanta.com", sipuri.SIP, "sip:alice@atlanta.com", "mixed case sip"},
+		{"sIpS:alice@atlanta.com", sipuri.SIPS, "sips:alice@atlanta.com", "mixed case sips"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			sipURI, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf(`failed to parse SIP URI %q, %v error`, test.uri, err)
+			}
+
+			equalF(t, test.proto, sipURI.Proto(), "protocol mismatch in %s", test.msg)
+			equalF(t, test.canon, sipURI.String(), "reconstructing string %s", test.msg)
+		}
+	}
+}
+
+func TestParseWithScheme(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri    string
+		scheme string
+		proto  sipuri.Protocol
+	}
+
+	tests := []test{
+		{"SIP:alice@atlanta.com", "SIP", sipuri.SIP},
+		{"sip:alice@atlanta.com", "sip", sipuri.SIP},
+		{"SiPs:alice@atlanta.com", "SiPs", sipuri.SIPS},
+		{"sips:alice@atlanta.com", "sips", sipuri.SIPS},
+	}
+
+	for _, test := range tests {
+		uri, scheme, err := sipuri.ParseWithScheme(test.uri)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, test.scheme, scheme, "scheme of %q", test.uri)
+		equalF(t, test.proto, uri.Proto(), "protocol of %q", test.uri)
+	}
+
+	_, scheme, err := sipuri.ParseWithScheme(" Sips:alice@atlanta.com ", sipuri.WithTrimSpace())
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "Sips", scheme, "scheme after trimmed space")
+
+	_, scheme, err = sipuri.ParseWithScheme("tel:+1-212-555-1212")
+	if !errors.Is(err, sipuri.ErrInvalidScheme) {
+		t.Fatalf("expected invalid scheme error but got %q", err)
+	}
+
+	equalF(t, "", scheme, "no scheme on error")
+}
+
+func TestParseBytes(t *testing.T) {
+	t.Parallel()
+
+	input := []byte("sip:alice@atlanta.com;transport=tcp")
+
+	sipURI, err := sipuri.ParseBytes(input)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "alice", sipURI.User(), "user mismatch")
+	equalF(t, "atlanta.com", sipURI.Host(), "host mismatch")
+	equalF(t, "sip:alice@atlanta.com;transport=tcp", sipURI.String(), "reconstructing string")
+
+	// The URI must not alias the input.
+	copy(input, "sip:bobby@biloxi.com;transport=udp")
+
+	equalF(t, "alice", sipURI.User(), "user aliasing the input")
+	equalF(t, "tcp", sipURI.Params().Get("transport"), "params aliasing the input")
+
+	input = []byte("sip:alice@atlanta.com;transport=tcp")
+
+	sipURI, err = sipuri.ParseBytes(input, sipuri.WithAliasedBytes())
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "sip:alice@atlanta.com;transport=tcp", sipURI.String(), "aliased string")
+
+	// The URI aliases the input when opted in.
+	copy(input, "sip:bobby")
+
+	equalF(t, "bobby", sipURI.User(), "user not aliasing the input")
+
+	_, err = sipuri.ParseBytes([]byte("sip:@atlanta.com"))
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MissingUser}) {
+		t.Fatalf("expected missing user error but got %q", err)
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	t.Parallel()
+
+	const input = "SIP:%61lice@atlanta.com;x=%2f;lr?subject=a%2fb"
+
+	for _, lazy := range []bool{false, true} {
+		parse := sipuri.ParseWithOptions
+		if lazy {
+			parse = lazily(parse, sipuri.WithLazy())
+		}
+
+		uri, err := parse(input, sipuri.WithRaw())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, input, uri.String(), "input returned verbatim")
+		equalF(t, input, uri.Clone().String(), "clone keeps the input")
+		equalF(t, "alice", uri.User(), "user decoded")
+		equalF(t, "/", uri.Params().Get("x"), "params decoded")
+
+		// Lazy headers yet to be loaded are written as given.
+		headers := "subject=a%2Fb"
+		if lazy {
+			headers = "subject=a%2fb"
+		}
+
+		equalF(t, "sip:alice@atlanta.com;lr;ttl=1;x=%2F?"+headers, uri.WithParam("ttl", "1").String(), "modified uri rebuilt")
+		equalF(t, "sip:alice@atlanta.com;lr;x=%2F?"+headers, uri.Normalize().String(), "normalized uri rebuilt")
+
+		uri, err = parse(input)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		uri.Params().Get("x")
+
+		equalF(t, "sip:alice@atlanta.com;lr;x=%2F?"+headers, uri.String(), "rebuilt without option")
+	}
+}
+
+func TestParseEmptyPairs(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		eager string
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:h;lr;", "sip:h;lr", "trailing param separator"},
+		{"sip:h;;lr", "sip:h;lr", "empty param"},
+		{"sip:0?&", "sip:0?", "only empty header"},
+		{"sip:0?0&", "sip:0?0", "trailing header separator"},
+		{"sip:h;lr;?a=1&&b=2", "sip:h;lr?a=1&b=2", "empty params and headers"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			reparsed, err := parse(uri.String())
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, true, uri.Equal(*reparsed), "round trip equal in %s", test.msg)
+		}
+
+		uri, err := sipuri.Parse(test.uri)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, test.eager, uri.String(), "string without empty pairs in %s", test.msg)
+	}
+}
+
+func TestParseLazyString(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		eager string
+		lazy  string
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com;b=1;a=%2f", "sip:alice@atlanta.com;a=%2F;b=1", "sip:alice@atlanta.com;b=1;a=%2f", "params order and encoding"},
+		{"sip:alice@atlanta.com?to=bob&subject=a%20b", "sip:alice@atlanta.com?subject=a%20b&to=bob", "sip:alice@atlanta.com?to=bob&subject=a%20b", "headers order"},
+		{"SIP:%61lice@atlanta.com;transport=tcp", "sip:alice@atlanta.com;transport=tcp", "sip:alice@atlanta.com;transport=tcp", "only params and headers kept"},
+	}
+
+	for _, test := range tests {
+		eager, err := sipuri.Parse(test.uri)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		lazy, err := sipuri.ParseLazy(test.uri)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, test.eager, eager.String(), "eager string in %s", test.msg)
+		equalF(t, test.lazy, lazy.String(), "lazy string in %s", test.msg)
+		equalF(t, test.lazy, lazy.Clone().String(), "cloned lazy string in %s", test.msg)
+
+		// Once loaded the lazy store is encoded like the eager store.
+		lazy.Params().Len()
+		lazy.Headers().Len()
+
+		equalF(t, test.eager, lazy.String(), "loaded lazy string in %s", test.msg)
+	}
+}
+
+func TestParseHeaderSeparator(t *testing.T) {
+	t.Parallel()
+
+	for _, lazy := range []bool{false, true} {
+		parse := sipuri.ParseWithOptions
+		if lazy {
+			parse = lazily(parse, sipuri.WithLazy())
+		}
+
+		uri, err := parse("sip:alice@atlanta.com?a=1;b=2", sipuri.WithHeaderSeparator(";"))
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "sip:alice@atlanta.com?a=1&b=2", uri.String(), "headers joined with &")
+		equalF(t, "1", uri.Headers().Get("a"), "first header")
+		equalF(t, "2", uri.Headers().Get("b"), "second header")
+
+		uri, err = parse("sip:alice@atlanta.com?a=1;b=2")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "1;b=2", uri.Headers().Get("a"), "split on & by default")
+
+		_, err = parse("sip:alice@atlanta.com?a=1;b=%2", sipuri.WithHeaderSeparator(";"))
+		equalF(t, "sip: malformed uri: malformed headers: sip: invalid URL escape \"%2\"", err.Error(), "error offset")
+		offsetF(t, 28, err, "error offset")
+	}
+}
+
+func TestParseTrimSpace(t *testing.T) {
+	t.Parallel()
+
+	for _, lazy := range []bool{false, true} {
+		parse := sipuri.ParseWithOptions
+		if lazy {
+			parse = lazily(parse, sipuri.WithLazy())
+		}
+
+		for _, input := range []string{" sip:alice@atlanta.com ", "\tsip:alice@atlanta.com\r\n", "sip:alice@atlanta.com"} {
+			uri, err := parse(input, sipuri.WithTrimSpace())
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, "sip:alice@atlanta.com", uri.String(), "trimmed %q", input)
+		}
+
+		uri, err := parse(" sip:al ice@atlanta.com;x=a b ", sipuri.WithTrimSpace())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "al ice", uri.User(), "internal space kept")
+		equalF(t, "a b", uri.Params().Get("x"), "internal space kept")
+
+		_, err = parse(" sip:alice@atlanta.com ")
+		if !errors.Is(err, sipuri.ErrInvalidScheme) {
+			t.Fatalf("expected invalid scheme error but got %q", err)
+		}
+
+		_, err = parse("  sip:alice@atlanta.com;%xx ", sipuri.WithTrimSpace())
+		equalF(t, `sip: malformed uri: malformed params: sip: invalid URL escape "%xx"`, err.Error(), "offset in untrimmed input")
+		offsetF(t, 24, err, "offset in untrimmed input")
+	}
+}
+
+func TestParseMaxLength(t *testing.T) {
+	t.Parallel()
+
+	huge := "sip:alice@atlanta.com;x=" + strings.Repeat("%41", 1<<20)
+
+	for _, lazy := range []bool{false, true} {
+		parse := sipuri.ParseWithOptions
+		if lazy {
+			parse = lazily(parse, sipuri.WithLazy())
+		}
+
+		uri, err := parse(huge)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, 1<<20, len(uri.Params().Get("x")), "no limit by default")
+
+		_, err = parse(huge, sipuri.WithMaxLength(8192))
+		if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.TooLong}) {
+			t.Fatalf("expected too long error but got %q", err)
+		}
+
+		equalF(t, "sip: malformed uri: too long", err.Error(), "limit offset")
+		offsetF(t, 8192, err, "limit offset")
+
+		_, err = parse("sip:alice@atlanta.com", sipuri.WithMaxLength(20))
+		if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.TooLong}) {
+			t.Fatalf("expected too long error but got %q", err)
+		}
+
+		if _, err := parse("sip:alice@atlanta.com", sipuri.WithMaxLength(21)); err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		if _, err := parse(huge, sipuri.WithMaxLength(0)); err != nil {
+			t.Fatalf("err %v", err)
+		}
+	}
+
+	equalF(t, true, sipuri.Valid(huge), "valid agrees")
+}
+
+func TestParseNearMaxLengthRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	// The input is just within the limit but its string escapes each of the
+	// user bytes, so is near three times longer.
+	input := "sip:" + strings.Repeat("\xc0", 2700) + "@atlanta.com;transport=tcp"
+
+	for _, lazy := range []bool{false, true} {
+		parse := sipuri.ParseWithOptions
+		if lazy {
+			parse = lazily(parse, sipuri.WithLazy())
+		}
+
+		uri, err := parse(input, sipuri.WithMaxLength(len(input)))
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		other, err := parse(uri.String())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, true, uri.Equal(*other), "round trip")
+		equalF(t, uri.String(), other.String(), "same string")
+	}
+}
+
+// TestParseMaxLengthAllocs is not parallel as [testing.AllocsPerRun] panics
+// when run in parallel.
+//
+//nolint:paralleltest
+func TestParseMaxLengthAllocs(t *testing.T) {
+	huge := "sip:alice@atlanta.com;x=" + strings.Repeat("%41", 1<<20)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		_, _ = sipuri.ParseValue(huge, sipuri.WithMaxLength(8192))
+	})
+
+	equalF(t, true, allocs <= 2, "rejected without decoding, %v allocs", allocs)
+}
+
+func TestParseHostDelimiters(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse(`sip:alice@ho<st>"x"`)
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, `ho<st>"x"`, uri.Host(), "host decoded")
+		equalF(t, "sip:alice@ho%3Cst%3E%22x%22", uri.String(), "delimiters escaped")
+
+		again, err := parse(uri.String())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, uri.Host(), again.Host(), "escaped host round trips")
 	}
 }
 
@@ -174,6 +610,16 @@ func TestParseError(t *testing.T) {
 			sipuri.ErrInvalidScheme,
 			"no scheme present",
 		},
+		{
+			"SIPX:user@example.sip.twilio.com",
+			sipuri.ErrInvalidScheme,
+			"unknown uppercase scheme",
+		},
+		{
+			"SI",
+			sipuri.ErrInvalidScheme,
+			"truncated scheme",
+		},
 		{
 			"sip:user@",
 			sipuri.MalformedURIError{Cause: sipuri.MissingHost},
@@ -199,6 +645,16 @@ func TestParseError(t *testing.T) {
 			sipuri.MalformedURIError{Cause: sipuri.MissingUser},
 			"no user present",
 		},
+		{
+			"sip::secret@example.sip.twilio.com",
+			sipuri.MalformedURIError{Cause: sipuri.MissingUser},
+			"password without user",
+		},
+		{
+			"sip::@example.sip.twilio.com",
+			sipuri.MalformedURIError{Cause: sipuri.MissingUser},
+			"empty password without user",
+		},
 		{
 			"sip:%xx@example.sip.twilio.com",
 			sipuri.MalformedURIError{Cause: sipuri.MalformedUser},
@@ -241,9 +697,74 @@ func TestParseError(t *testing.T) {
 
 			equalF(t, (*sipuri.URI)(nil), nul, "nil received %s", test.msg)
 		}
+
+		equalF(t, false, sipuri.Valid(test.uri), "invalid in %s", test.msg)
 	}
 }
 
+func TestParseUnbracketedIPv6(t *testing.T) {
+	t.Parallel()
+
+	for _, uri := range []string{"sip:user@::1", "sip:user@2001:db8::1", "sip:user@2001:db8::1;transport=tcp"} {
+		for _, parse := range parseFuncs {
+			_, err := parse(uri)
+			if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedHost}) || !errors.Is(err, sipuri.ErrUnbracketedIPv6) {
+				t.Fatalf("expected unbracketed ipv6 error but got %q for %s", err, uri)
+			}
+		}
+	}
+
+	for _, uri := range []string{"sip:user@[::1]", "sip:user@[2001:db8::1]:5060", "sip:user@host:5060"} {
+		for _, parse := range parseFuncs {
+			if _, err := parse(uri); err != nil {
+				t.Fatalf("unexpected error %q for %s", err, uri)
+			}
+		}
+	}
+}
+
+func TestParseErrorOffset(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri    string
+		offset int
+		msg    string
+	}
+
+	tests := []test{
+		{"sip:@example.com", 4, "missing user"},
+		{"sip:user@", 9, "missing host"},
+		{"sip:user@;transport=tcp", 9, "empty host"},
+		{"sip:us%xxer@example.com", 6, "malformed user"},
+		{"sips:user@exa%2mple.com", 13, "malformed host"},
+		{"sip:user@[::1", 9, "malformed ipv6 host"},
+		{"sip:user@example.com;transport=tcp;user=%zz", 40, "malformed param value"},
+		{"sip:user@example.com;a;%", 23, "truncated param escape"},
+		{"sip:user@example.com;a?subject=x&%xx=1", 33, "malformed header key"},
+		{"SIP:example.com?subject=%", 24, "truncated header escape"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			_, err := parse(test.uri)
+
+			var malformed sipuri.MalformedURIError
+			if !errors.As(err, &malformed) {
+				t.Fatalf(`expected malformed uri error but got %q in %s`, err, test.msg)
+			}
+
+			equalF(t, test.offset, malformed.Offset, "offset mismatch in %s", test.msg)
+		}
+	}
+
+	_, err := sipuri.Parse("sip:us%xxer@example.com")
+	equalF(t, `sip: malformed uri: malformed user: sip: invalid URL escape "%xx"`, err.Error(), "error message")
+
+	_, err = sipuri.ParseWithOptions("sip:+1-800-FLOWERS@gw.com;user=phone", sipuri.WithPhoneUserValidation())
+	equalF(t, `sip: malformed uri: malformed user`, err.Error(), "validation error message")
+}
+
 func ExampleParse() {
 	sipURI, err := sipuri.Parse("sip:user:password@host:port;uri-parameters?headers")
 	if err != nil {
@@ -266,7 +787,144 @@ func ExampleParse() {
 	// host:port
 	// map[uri-parameters:[]]
 	// map[headers:[]]
-	// sip:user:password@host:port;uri-parameters=?headers=
+	// sip:user:password@host:port;uri-parameters?headers
+}
+
+// rfcExamples are the example URIs of RFC 3261 §19.1.3.
+//
+//nolint:gochecknoglobals
+var rfcExamples = []string{
+	"sip:alice@atlanta.com",
+	"sip:alice:secretword@atlanta.com;transport=tcp",
+	"sips:alice@atlanta.com?subject=project%20x&priority=urgent",
+	"sip:+1-212-555-1212:1234@gateway.com;user=phone",
+	"sips:1212@gateway.com",
+	"sip:alice@192.0.2.4",
+	"sip:atlanta.com;method=REGISTER?to=alice%40atlanta.com",
+	"sip:alice;day=tuesday@atlanta.com",
+}
+
+// FuzzParse checks parsing never panics and any URI which parses round trips
+// through [sipuri.URI.String] to an equal URI with the same string.
+//
+//	go test -fuzz=FuzzParse
+func FuzzParse(f *testing.F) {
+	for _, uri := range rfcExamples {
+		f.Add(uri)
+	}
+
+	f.Add("sip:user:password@host:port;uri-parameters?headers")
+	f.Add("sips:[2001:db8::2:1]:5061;transport=tls")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		_, _ = sipuri.ParseLazy(input)
+
+		first, err := sipuri.Parse(input)
+
+		equalF(t, err == nil, sipuri.Valid(input), "valid agrees with parse of %q", input)
+
+		if err != nil {
+			return
+		}
+
+		second, err := sipuri.Parse(first.String())
+		if err != nil {
+			t.Fatalf("failed to parse %q, the string of %q: %v", first.String(), input, err)
+		}
+
+		equalF(t, first.String(), second.String(), "string round trip of %q", input)
+
+		if !first.Equal(*second) {
+			t.Fatalf("round trip of %q is not equal", input)
+		}
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for _, uri := range rfcExamples {
+			if _, err := sipuri.Parse(uri); err != nil {
+				b.Fatalf("err %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkParseValue(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for _, uri := range rfcExamples {
+			if _, err := sipuri.ParseValue(uri); err != nil {
+				b.Fatalf("err %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	inputs := make([][]byte, len(rfcExamples))
+	for i, uri := range rfcExamples {
+		inputs[i] = []byte(uri)
+	}
+
+	b.Run("copy", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			for _, uri := range inputs {
+				if _, err := sipuri.ParseBytes(uri); err != nil {
+					b.Fatalf("err %v", err)
+				}
+			}
+		}
+	})
+
+	b.Run("alias", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			for _, uri := range inputs {
+				if _, err := sipuri.ParseBytes(uri, sipuri.WithAliasedBytes()); err != nil {
+					b.Fatalf("err %v", err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkValid(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for _, uri := range rfcExamples {
+			if !sipuri.Valid(uri) {
+				b.Fatalf("invalid %q", uri)
+			}
+		}
+	}
+}
+
+// lazily returns the parse function applying [sipuri.WithLazy], whose type is
+// unexported so cannot be named.
+func lazily[T any](parse func(string, ...T) (*sipuri.URI, error), lazy T) func(string, ...T) (*sipuri.URI, error) {
+	return func(uri string, opts ...T) (*sipuri.URI, error) {
+		return parse(uri, append(opts, lazy)...)
+	}
+}
+
+// offsetF fails unless the error is a [sipuri.MalformedURIError] at offset e.
+func offsetF(t *testing.T, e int, err error, m string) {
+	t.Helper()
+
+	var malformed sipuri.MalformedURIError
+	if !errors.As(err, &malformed) {
+		t.Fatalf(`expected malformed uri error but got %q, %s`, err, m)
+	}
+
+	equalF(t, e, malformed.Offset, m)
 }
 
 func equalF(t *testing.T, e interface{}, g interface{}, m string, a ...interface{}) {
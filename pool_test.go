@@ -0,0 +1,67 @@
+package sipuri_test
+
+import (
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestPooledStores(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 3; i++ {
+		uri, err := sipuri.ParseWithOptions("sip:alice@atlanta.com;transport=tcp;lr?subject=project%20x", sipuri.WithPooledStores())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		clone := uri.Clone()
+		moved := uri.WithPort("5070")
+
+		sipuri.ReleaseURI(&clone)
+		sipuri.ReleaseURI(&moved)
+
+		equalF(t, "lr&transport=tcp", uri.Params().Encode(), "params released by a copy")
+		equalF(t, "lr&transport=tcp", clone.Params().Encode(), "clone released")
+
+		equalF(t, "lr&transport=tcp", uri.Params().Encode(), "params decoded")
+		equalF(t, "subject=project%20x", uri.Headers().Encode(), "headers decoded")
+
+		sipuri.ReleaseURI(uri)
+
+		equalF(t, true, uri.Params().Empty(), "params released")
+		equalF(t, true, uri.Headers().Empty(), "headers released")
+		equalF(t, "lr&transport=tcp", clone.Params().Encode(), "clone kept params")
+		equalF(t, "subject=project%20x", clone.Headers().Encode(), "clone kept headers")
+
+		sipuri.ReleaseURI(uri)
+	}
+
+	_, err := sipuri.ParseWithOptions("sip:alice@atlanta.com;%xx", sipuri.WithPooledStores())
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	uri, err := sipuri.Parse("sip:alice@atlanta.com;transport=tcp")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	sipuri.ReleaseURI(uri)
+	equalF(t, "tcp", uri.Params().Get("transport"), "unpooled uri not released")
+}
+
+func BenchmarkParsePooled(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for _, uri := range rfcExamples {
+			sipURI, err := sipuri.ParseWithOptions(uri, sipuri.WithPooledStores())
+			if err != nil {
+				b.Fatalf("err %v", err)
+			}
+
+			sipuri.ReleaseURI(sipURI)
+		}
+	}
+}
@@ -8,7 +8,10 @@
 package sipuri
 
 import (
+	"io"
 	"net"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -27,6 +30,28 @@ const (
 	SIPS Protocol = true
 )
 
+// String returns the scheme name "sip" or "sips", without the trailing colon.
+func (p Protocol) String() string {
+	if p == SIPS {
+		return SIPSProtocol[:len(SIPSProtocol)-1]
+	}
+
+	return SIPProtocol[:len(SIPProtocol)-1]
+}
+
+// ParseScheme converts the scheme name "sip" or "sips", without the trailing
+// colon, into its [Protocol]. The name is matched case-insensitively.
+func ParseScheme(scheme string) (Protocol, bool) {
+	switch {
+	case strings.EqualFold(scheme, SIPProtocol[:len(SIPProtocol)-1]):
+		return SIP, true
+	case strings.EqualFold(scheme, SIPSProtocol[:len(SIPSProtocol)-1]):
+		return SIPS, true
+	default:
+		return SIP, false
+	}
+}
+
 // URI stores the components of that make up a SIP URI.
 type URI struct {
 	proto Protocol // default is SIP
@@ -37,9 +62,23 @@ type URI struct {
 	params  KeyValueStore
 	headers KeyValueStore
 
+	// The host split from the port, valid when hostSplit is set.
+	hostname  string
+	port      string
+	hostSplit bool
+
 	hadPass   bool
 	hadParam  bool
 	hadHeader bool
+
+	// The user and host as escaped in the input, see [URI.RawUser].
+	rawUser string
+	rawHost string
+
+	raw    string // the input when parsed with [WithRaw]
+	pooled bool   // parsed with [WithPooledStores]
+
+	err error // the first error of an option, returned by [NewStrict]
 }
 
 type uriOption func(u *URI)
@@ -58,6 +97,30 @@ func WithHeaders(headers KeyValueStore) uriOption {
 	}
 }
 
+// WithParamsFromValues sets the URI params to a copy of the [url.Values].
+func WithParamsFromValues(values url.Values) uriOption {
+	return func(u *URI) {
+		u.params = KeyValuePairs(values).Clone()
+	}
+}
+
+// WithTransport sets the transport param, lower cased into its canonical form.
+// Unlike [WithParams] it merges into any params set by earlier options.
+func WithTransport(transport string) uriOption {
+	return func(u *URI) {
+		u.params = storeWith(u.Params(), "transport", strings.ToLower(transport), true)
+	}
+}
+
+// WithPort sets the port of the host, bracketing an IPv6 address as needed.
+// An empty port removes any port.
+func WithPort(port string) uriOption {
+	return func(u *URI) {
+		u.host = joinHostPort(u.HostName(), port)
+		u.rawHost = ""
+	}
+}
+
 // WithPassword allows the password portion of the user-info to be set.
 //
 // Use of a password is not advised and is inherently insecure. Use other
@@ -68,6 +131,21 @@ func WithPassword(pass string) uriOption {
 	}
 }
 
+// WithUserParam appends the param to the user, such as day=tuesday in
+// sip:alice;day=tuesday@atlanta.com of §19.1.3, which is part of the user
+// rather than a URI param. An empty value appends the key alone.
+func WithUserParam(key, value string) uriOption {
+	return func(u *URI) {
+		u.user += ";" + key
+
+		if value != "" {
+			u.user += "=" + value
+		}
+
+		u.rawUser = ""
+	}
+}
+
 // Secure upgrades the URI to the SIPS protocol.
 func Secure() uriOption {
 	return func(u *URI) {
@@ -75,8 +153,77 @@ func Secure() uriOption {
 	}
 }
 
+// WithScheme sets the protocol from the scheme name "sip" or "sips", matched
+// case-insensitively as by [ParseScheme]. An unknown scheme leaves the
+// protocol unchanged, or is returned as [ErrInvalidScheme] by [NewStrict].
+func WithScheme(scheme string) uriOption {
+	return func(u *URI) {
+		proto, ok := ParseScheme(scheme)
+		if !ok {
+			u.fail(ErrInvalidScheme)
+
+			return
+		}
+
+		u.proto = proto
+	}
+}
+
 // New constructs a SIP URI with the given options.
+//
+// The user and host are decoded values, escaped as needed by [URI.String], so
+// "%41" is written as "%2541". Use [NewEncoded] for percent-encoded input.
+//
+// The user is otherwise taken verbatim. A ';' in the user, as in
+// "alice;day=tuesday", is left unescaped as part of the user rather than
+// starting the URI params, see [WithUserParam].
 func New(user, host string, opts ...uriOption) URI {
+	// An error of an option is only returned by NewStrict.
+	u, _ := newURI(user, host, opts)
+
+	// A malformed host is reported by each call to SplitHostPort instead.
+	_ = u.splitHost()
+
+	return u
+}
+
+// NewStrict constructs a SIP URI like [New] but checks the result as
+// [ParseStrict] would, along with any error of the options such as an unknown
+// scheme given to [WithScheme].
+//
+// A password without a user returns a [MalformedURIError] with the
+// [MissingUser] cause, and a missing or malformed host with the [MissingHost]
+// or [MalformedHost] cause. Components which are not valid UTF-8
+// or contain control characters or spaces return a [MalformedURIError]
+// wrapping [ErrInvalidUTF8] or [ErrControlCharacter].
+func NewStrict(user, host string, opts ...uriOption) (URI, error) {
+	u, err := newURI(user, host, opts)
+	if err != nil {
+		return URI{}, err
+	}
+
+	if u.user == "" && u.pass != "" {
+		return URI{}, MalformedURIError{Cause: MissingUser}
+	}
+
+	if u.host == "" {
+		return URI{}, MalformedURIError{Cause: MissingHost}
+	}
+
+	if err := u.splitHost(); err != nil {
+		return URI{}, err
+	}
+
+	if err := newParser(strictOptions()).validate(u); err != nil {
+		return URI{}, err
+	}
+
+	return u, nil
+}
+
+// newURI applies the options to the URI, returning the first error of an
+// option separately.
+func newURI(user, host string, opts []uriOption) (URI, error) {
 	u := URI{
 		user: user,
 		host: host,
@@ -86,25 +233,144 @@ func New(user, host string, opts ...uriOption) URI {
 		opt(&u)
 	}
 
-	return u
+	err := u.err
+	u.err = nil
+
+	return u, err
+}
+
+// fail records the first error of an option.
+func (sipURI *URI) fail(err error) {
+	if sipURI.err == nil {
+		sipURI.err = err
+	}
+}
+
+// NewEncoded constructs a SIP URI like [New] but from a percent-encoded user
+// and host, such as "%41lice", which are decoded rather than escaped again.
+//
+// A malformed escape returns a [MalformedURIError] with the [MalformedUser] or
+// [MalformedHost] cause.
+func NewEncoded(user, host string, opts ...uriOption) (URI, error) {
+	decodedUser, err := Unescape(user)
+	if err != nil {
+		return URI{}, MalformedURIError{Cause: MalformedUser, Err: err}
+	}
+
+	decodedHost, err := Unescape(host)
+	if err != nil {
+		return URI{}, MalformedURIError{Cause: MalformedHost, Err: err}
+	}
+
+	return New(decodedUser, decodedHost, opts...), nil
+}
+
+// Clone returns a deep copy of the URI which can be modified without affecting
+// the original. The quirks of the input are preserved so both URIs produce the
+// same [URI.String] output.
+//
+// Params and headers stores are copied when they are one of the types provided
+// by this package, other implementations are shared.
+func (sipURI URI) Clone() URI {
+	clone := sipURI
+	clone.params = cloneStore(sipURI.params)
+	clone.headers = cloneStore(sipURI.headers)
+	clone.pooled = false
+
+	return clone
+}
+
+// WithParam returns a copy of the URI with the value added to the param key.
+// The original URI is not modified.
+func (sipURI URI) WithParam(key, value string) URI {
+	sipURI.params = storeWith(sipURI.Params(), key, value, false)
+	sipURI.hadParam = true
+	sipURI.raw = ""
+	sipURI.pooled = false
+
+	return sipURI
+}
+
+// WithHeader returns a copy of the URI with the value added to the header key.
+// The original URI is not modified.
+func (sipURI URI) WithHeader(key, value string) URI {
+	sipURI.headers = storeWith(sipURI.Headers(), key, value, false)
+	sipURI.hadHeader = true
+	sipURI.raw = ""
+	sipURI.pooled = false
+
+	return sipURI
+}
+
+// RequestURI returns a copy of the URI for use as the Request-URI of a
+// request, without the headers and method param which the table of §19.1.1
+// does not allow there, matching the param name case-insensitively. The
+// original URI is not modified.
+func (sipURI URI) RequestURI() URI {
+	for _, key := range sipURI.Params().Keys() {
+		if strings.EqualFold(key, "method") {
+			sipURI.params = storeWithout(sipURI.Params(), key)
+			sipURI.hadParam = !sipURI.params.Empty()
+		}
+	}
+
+	sipURI.headers = EmptyStore{}
+	sipURI.hadHeader = false
+	sipURI.raw = ""
+	sipURI.pooled = false
+
+	return sipURI
+}
+
+// WithPort returns a copy of the URI with the port of the host set, bracketing
+// an IPv6 address as needed. An empty port removes any port. The original URI
+// is not modified.
+func (sipURI URI) WithPort(port string) URI {
+	WithPort(port)(&sipURI)
+	sipURI.raw = ""
+	sipURI.pooled = false
+	_ = sipURI.splitHost()
+
+	return sipURI
 }
 
 // Transport returns the Transport protocols that would be used to make a
 // connection to the host.
 func (sipURI URI) Transport() string {
-	if transport := sipURI.Params().Get("transport"); transport != "" {
+	return sipURI.TransportWith(rfcDefaults)
+}
+
+// TransportWith returns the transport like [URI.Transport] but with the
+// default of the scheme taken from the given defaults.
+func (sipURI URI) TransportWith(defaults Defaults) string {
+	if transport := sipURI.param("transport"); transport != "" {
+		// Avoid allocating for the common transports.
+		for _, known := range [...]string{"UDP", "TCP", "TLS", "SCTP", "WS", "WSS"} {
+			if strings.EqualFold(transport, known) {
+				return known
+			}
+		}
+
 		return strings.ToUpper(transport)
 	}
 
-	// §19.1.2 "The default transport is scheme dependent. For sip:, it is UDP. For sips:, it is TCP."
-	switch sipURI.proto {
-	case SIP:
-		return "UDP"
-	case SIPS:
-		return "TCP"
-	default:
-		panic("unreachable")
+	return defaults.Transport[sipURI.proto]
+}
+
+// UsesTLS returns if a connection to the host would be secured by TLS. That is
+// when the scheme is SIPS, regardless of transport, or otherwise the transport
+// param is TLS or WSS.
+func (sipURI URI) UsesTLS() bool {
+	if sipURI.proto == SIPS {
+		return true
+	}
+
+	switch sipURI.Transport() {
+	case "TLS", "WSS":
+		return true
 	}
+
+	return false
 }
 
 // Port returns the port split from the host portion returning the
@@ -112,76 +378,478 @@ func (sipURI URI) Transport() string {
 //
 // Returns an empty string in the case of the sip proto & unexpected transport.
 func (sipURI URI) Port() string {
+	return sipURI.PortWith(rfcDefaults)
+}
+
+// PortWith returns the port like [URI.Port] but with the default port, and
+// the transport it depends on, taken from the given defaults.
+func (sipURI URI) PortWith(defaults Defaults) string {
 	_, port, _ := sipURI.SplitHostPort()
 
 	if port != "" {
 		return port
 	}
 
+	ports := defaults.Port[sipURI.proto]
+
+	if port, ok := ports[sipURI.TransportWith(defaults)]; ok {
+		return port
+	}
+
+	return ports[""]
+}
+
+// Defaults holds the default transport and port of each scheme used by
+// [URI.TransportWith] and [URI.PortWith], for deployments which differ from
+// the defaults of the RFCs. See [RFCDefaults].
+type Defaults struct {
+	// Transport is the upper cased transport of each scheme when the URI has
+	// no transport param.
+	Transport map[Protocol]string
+	// Port is the port of each scheme and upper cased transport when the
+	// host has no port. The empty transport is the port of any transport not
+	// listed.
+	Port map[Protocol]map[string]string
+}
+
+// RFCDefaults returns a copy of the defaults used by [URI.Transport] and
+// [URI.Port], which may be modified and given to [URI.PortWith].
+func RFCDefaults() Defaults {
+	defaults := Defaults{
+		Transport: make(map[Protocol]string, len(rfcDefaults.Transport)),
+		Port:      make(map[Protocol]map[string]string, len(rfcDefaults.Port)),
+	}
+
+	for proto, transport := range rfcDefaults.Transport {
+		defaults.Transport[proto] = transport
+	}
+
+	for proto, ports := range rfcDefaults.Port {
+		defaults.Port[proto] = make(map[string]string, len(ports))
+
+		for transport, port := range ports {
+			defaults.Port[proto][transport] = port
+		}
+	}
+
+	return defaults
+}
+
+//nolint:gochecknoglobals
+var rfcDefaults = Defaults{
+	// §19.1.2 "The default transport is scheme dependent. For sip:, it is UDP. For sips:, it is TCP."
+	Transport: map[Protocol]string{SIP: "UDP", SIPS: "TCP"},
 	// §19.1.2 says "The default port value is transport and scheme dependent.
 	// The default is 5060 for sip: using UDP, TCP, or SCTP. The default
 	// is 5061 for sip: using TLS over TCP and sips: over TCP."
-	if sipURI.proto == SIPS {
-		return "5061"
+	//
+	// RFC 7118 §5 SIP over WebSocket follows the HTTP defaults of RFC 6455,
+	// 80 for ws and 443 for wss, regardless of scheme.
+	Port: map[Protocol]map[string]string{
+		SIP: {
+			"UDP": "5060", "TCP": "5060", "SCTP": "5060", "TLS": "5061",
+			"WS": "80", "WSS": "443",
+		},
+		SIPS: {
+			"":   "5061",
+			"WS": "80", "WSS": "443",
+		},
+	},
+}
+
+// Maddr returns the maddr param which overrides the address derived from the
+// host. Empty string otherwise.
+func (sipURI URI) Maddr() string {
+	return sipURI.Params().Get("maddr")
+}
+
+// Method returns the method param, the SIP method of the request formed from
+// the URI, upper cased as the methods defined by §25.1 are, such as REGISTER
+// for sip:registrar.biloxi.com;method=register. Empty string otherwise.
+func (sipURI URI) Method() string {
+	return strings.ToUpper(sipURI.Params().Get("method"))
+}
+
+// TTL returns the ttl param used with multicast. The bool reports if the param
+// was present and a valid number in the range 0-255.
+func (sipURI URI) TTL() (int, bool) {
+	ttl, err := strconv.ParseUint(sipURI.Params().Get("ttl"), 10, 8)
+	if err != nil {
+		return 0, false
 	}
 
-	switch sipURI.Transport() {
-	case "UDP", "TCP", "SCTP":
-		return "5060"
-	// "The default is 5061 for sip: using TLS over TCP"
-	case "TLS":
-		return "5061"
+	return int(ttl), true
+}
+
+// Target returns the target param of RFC 4458 parsed as a URI, the original
+// target of a request retargeted such as to voicemail. The value is decoded
+// along with the other params so sip:vm@host;target=sip%3Aalice%40atlanta.com
+// has the target sip:alice@atlanta.com.
+//
+// A nil URI is returned without error when the param is absent. Any error
+// from [Parse] is returned as is.
+func (sipURI URI) Target() (*URI, error) {
+	values := sipURI.Params().Values("target")
+	if len(values) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	return Parse(values[0])
+}
+
+// Cause returns the cause param of RFC 4458, the SIP status code, such as
+// 302 or 486, for which the request was retargeted. The bool reports if the
+// param was present and a status code in the range 100-699.
+func (sipURI URI) Cause() (int, bool) {
+	const minStatus, maxStatus = 100, 699
+
+	cause, err := strconv.Atoi(sipURI.Params().Get("cause"))
+	if err != nil || cause < minStatus || cause > maxStatus {
+		return 0, false
+	}
+
+	return cause, true
+}
+
+// LooseRouting returns if the lr param is present, regardless of its value,
+// indicating the element responsible for the resource implements loose routing.
+func (sipURI URI) LooseRouting() bool {
+	_, ok := pairsOf(sipURI.Params())["lr"]
+
+	return ok
+}
+
+// Outbound returns if the ob param is present, regardless of its value,
+// indicating the proxy supports SIP Outbound as per RFC 5626 §5.4.
+func (sipURI URI) Outbound() bool {
+	_, ok := pairsOf(sipURI.Params())["ob"]
+
+	return ok
+}
+
+// PhoneContext returns the phone-context parameter of the telephone-subscriber
+// in the user portion when the URI has the user=phone param, such as
+// "+1-914-555" for sip:863-1234;phone-context=+1-914-555@gw.com;user=phone.
+// Empty string otherwise.
+func (sipURI URI) PhoneContext() string {
+	if !strings.EqualFold(sipURI.Params().Get("user"), "phone") {
+		return ""
+	}
+
+	_, params, _ := strings.Cut(sipURI.user, ";")
+
+	for params != "" {
+		var param string
+
+		param, params, _ = strings.Cut(params, ";")
+
+		if key, value, _ := strings.Cut(param, "="); strings.EqualFold(key, "phone-context") {
+			return value
+		}
 	}
 
 	return ""
 }
 
+// GlobalNumber returns the telephone-subscriber of a URI with the user=phone
+// param as a global number, such as for an ENUM lookup. A global number is
+// returned as is, while a local number is prefixed by its phone-context when
+// that is a global number prefix. Visual separators are kept as given.
+//
+// The bool reports if a global number could be formed.
+func (sipURI URI) GlobalNumber() (string, bool) {
+	if !strings.EqualFold(sipURI.Params().Get("user"), "phone") {
+		return "", false
+	}
+
+	number, _, _ := strings.Cut(sipURI.user, ";")
+	if number == "" {
+		return "", false
+	}
+
+	if number[0] == '+' {
+		return number, validTelNumber(number, true)
+	}
+
+	context := sipURI.PhoneContext()
+	if context == "" || context[0] != '+' || !validTelNumber(context, true) || !validTelNumber(number, false) {
+		return "", false
+	}
+
+	return context + number, true
+}
+
+// PhoneNumber is the telephone-subscriber of a URI with the user=phone param
+// broken into its parts, as returned by [URI.ParsePhoneNumber].
+type PhoneNumber struct {
+	// Raw is the number as given in the user, without any parameters, such
+	// as +1-212-555-1212.
+	Raw string
+	// CountryCode is the E.164 country calling code of a global number, such
+	// as 1 for +1-212-555-1212. Empty for a local number.
+	CountryCode string
+	// NationalNumber is the rest of a global number after the country code,
+	// or the whole of a local number, without visual separators.
+	NationalNumber string
+	// IsGlobal reports if the number is global, either given with a leading
+	// '+' or qualified by a global number phone-context.
+	IsGlobal bool
+}
+
+// ParsePhoneNumber returns the telephone-subscriber of a URI with the
+// user=phone param broken into its country code and national number, such as
+// 1 and 2125551212 for sip:+1-212-555-1212@gateway.com;user=phone. A local
+// number is made global by its phone-context as with [URI.GlobalNumber].
+//
+// The bool reports if the user is a valid telephone-subscriber.
+func (sipURI URI) ParsePhoneNumber() (PhoneNumber, bool) {
+	if !strings.EqualFold(sipURI.Params().Get("user"), "phone") {
+		return PhoneNumber{}, false
+	}
+
+	number, _, _ := strings.Cut(sipURI.user, ";")
+	if number == "" {
+		return PhoneNumber{}, false
+	}
+
+	phone := PhoneNumber{Raw: number}
+
+	if global, ok := sipURI.GlobalNumber(); ok {
+		digits := withoutVisualSeparators(global[1:])
+
+		code := countryCodeLen(digits)
+		if len(digits) <= code {
+			return PhoneNumber{}, false
+		}
+
+		phone.CountryCode, phone.NationalNumber = digits[:code], digits[code:]
+		phone.IsGlobal = true
+
+		return phone, true
+	}
+
+	if number[0] == '+' || !validTelNumber(number, false) {
+		return PhoneNumber{}, false
+	}
+
+	phone.NationalNumber = withoutVisualSeparators(number)
+
+	return phone, true
+}
+
+// withoutVisualSeparators removes the visual separators, and any spaces, from
+// the number.
+func withoutVisualSeparators(number string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '.', '(', ')', ' ':
+			return -1
+		}
+
+		return r
+	}, number)
+}
+
+// countryCodeLen returns the length of the E.164 country code the digits
+// begin with. The codes are prefix-free, North America and Russia have the
+// single digit codes 1 and 7, a few others have two digits and the rest have
+// three.
+func countryCodeLen(digits string) int {
+	const twoDigits, threeDigits = 2, 3
+
+	if digits == "" || digits[0] == '1' || digits[0] == '7' {
+		return 1
+	}
+
+	if len(digits) < twoDigits {
+		return threeDigits
+	}
+
+	switch digits[:twoDigits] {
+	case "20", "27", "30", "31", "32", "33", "34", "36", "39",
+		"40", "41", "43", "44", "45", "46", "47", "48", "49",
+		"51", "52", "53", "54", "55", "56", "57", "58",
+		"60", "61", "62", "63", "64", "65", "66",
+		"81", "82", "84", "86",
+		"90", "91", "92", "93", "94", "95", "98":
+		return twoDigits
+	}
+
+	return threeDigits
+}
+
+// GRUU returns the value of the gr param and if it is present, regardless of
+// a value, indicating the URI is a Globally Routable User Agent URI (RFC 5627).
+func (sipURI URI) GRUU() (string, bool) {
+	values, ok := pairsOf(sipURI.Params())["gr"]
+	if !ok || len(values) == 0 {
+		return "", ok
+	}
+
+	return values[0], true
+}
+
+// ConnectAddress returns the address to contact, without port. This is the
+// maddr param when present otherwise the host.
+//
+// Use [URI.Port] for the port to contact.
+func (sipURI URI) ConnectAddress() string {
+	if maddr := sipURI.Maddr(); maddr != "" {
+		return maddr
+	}
+
+	host, _, err := sipURI.SplitHostPort()
+	if err != nil {
+		return sipURI.host
+	}
+
+	return host
+}
+
 // String rebuilds the string representation of the URI respecting the quirks of the input.
+// When parsed with [WithRaw] the input is returned verbatim.
 //
-//nolint:cyclop
+// Params and headers parsed by [ParseLazy] which are yet to be loaded are
+// written as given, keeping their order and encoding. Otherwise they are
+// written sorted by key, unless parsed with [WithOrderedParams] or
+// [WithOrderedHeaders] to keep the order of the input.
 func (sipURI URI) String() string {
-	var builder strings.Builder
+	return string(sipURI.AppendString(nil))
+}
+
+// WriteTo writes the string representation of the URI, as returned by
+// [URI.String], to w implementing [io.WriterTo]. The URI is written with a
+// single call to w.
+func (sipURI URI) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(sipURI.AppendString(nil))
+
+	return int64(n), err //nolint:wrapcheck
+}
+
+// Redacted is like [URI.String] but replaces any password with "xxxxx", as
+// with [url.URL.Redacted], so the URI is safe to log.
+func (sipURI URI) Redacted() string {
+	if !sipURI.hadPass && sipURI.pass == "" {
+		return sipURI.String()
+	}
+
+	sipURI.pass = "xxxxx"
+	sipURI.raw = ""
+
+	return sipURI.String()
+}
+
+// Minimal is like [URI.String] but omits the ';' or '?' delimiter when there
+// are no params or headers, such as sip:h for the input sip:h;? so the URI is
+// written in its minimal form. Pairs well with [URI.Normalize].
+func (sipURI URI) Minimal() string {
+	sipURI.hadParam = false
+	sipURI.hadHeader = false
+	sipURI.raw = ""
+
+	return sipURI.String()
+}
+
+// AppendString appends the string representation of the URI, as returned by
+// [URI.String], to dst and returns the extended buffer.
+func (sipURI URI) AppendString(dst []byte) []byte {
+	return sipURI.appendString(dst, encodeHost)
+}
+
+// Lenient is like [URI.String] but leaves the sub-delims of the host, such as
+// ';' and '=', unescaped as earlier versions did, for consumers relying on
+// that output. Such a host may not parse back to the same URI.
+func (sipURI URI) Lenient() string {
+	return string(sipURI.appendString(nil, encodeHostLenient))
+}
+
+// appendString appends the string representation of the URI escaping the host
+// with the given mode.
+//
+//nolint:cyclop
+func (sipURI URI) appendString(dst []byte, hostMode encoding) []byte {
+	if sipURI.raw != "" {
+		return append(dst, sipURI.raw...)
+	}
 
 	switch sipURI.proto {
 	case SIPS:
-		builder.WriteString(SIPSProtocol)
+		dst = append(dst, SIPSProtocol...)
 	case SIP:
-		builder.WriteString(SIPProtocol)
+		dst = append(dst, SIPProtocol...)
 	}
 
 	if sipURI.user != "" {
-		builder.WriteString(escape(sipURI.user, encodeUserPassword))
+		dst = append(dst, escape(sipURI.user, encodeUser)...)
 
 		if sipURI.hadPass || sipURI.pass != "" {
-			builder.WriteRune(':')
+			dst = append(dst, ':')
 		}
 
 		if sipURI.pass != "" {
-			builder.WriteString(escape(sipURI.pass, encodeUserPassword))
+			dst = append(dst, escape(sipURI.pass, encodePassword)...)
 		}
 
-		builder.WriteByte('@') // only present when user is non-empty
+		dst = append(dst, '@') // only present when user is non-empty
 	}
 
-	builder.WriteString(escape(sipURI.host, encodeHost))
+	dst = appendHost(dst, sipURI.host, hostMode)
 
 	if sipURI.hadParam || !sipURI.Params().Empty() {
-		builder.WriteByte(';')
+		dst = append(dst, ';')
 	}
 
 	if !sipURI.Params().Empty() {
-		builder.WriteString(sipURI.Params().Encode())
+		dst = appendStore(dst, sipURI.Params(), ";")
 	}
 
 	if sipURI.hadHeader || !sipURI.Headers().Empty() {
-		builder.WriteByte('?')
+		dst = append(dst, '?')
 	}
 
 	if !sipURI.Headers().Empty() {
-		builder.WriteString(sipURI.Headers().Encode())
+		dst = appendStore(dst, sipURI.Headers(), "&")
+	}
+
+	return dst
+}
+
+// appendStore appends the encoded store joining the pairs with the separator,
+// using the input of a [LazyStore] yet to be loaded to avoid decoding it when
+// split on the same separator.
+func appendStore(dst []byte, store KeyValueStore, separator string) []byte {
+	switch store := store.(type) {
+	case KeyValuePairs:
+		return store.AppendEncode(dst, separator)
+	case *LazyStore:
+		if raw, unloaded := store.Raw(); unloaded && store.separator == separator {
+			return append(dst, raw...)
+		}
+
+		store.load()
+
+		return store.KeyValuePairs.AppendEncode(dst, separator)
+	}
+
+	var builder strings.Builder
+
+	// A strings.Builder never returns an error.
+	_, _ = store.WriteEncoded(&builder, separator)
+
+	return append(dst, builder.String()...)
+}
+
+// appendHost appends the escaped host. The '%' introducing the zone of an IPv6
+// reference, such as [fe80::1%25eth0], is escaped as "%25" per RFC 6874.
+func appendHost(dst []byte, host string, mode encoding) []byte {
+	if zone := strings.IndexByte(host, '%'); zone >= 0 && host[0] == '[' && zone < strings.IndexByte(host, ']') {
+		dst = append(dst, host[:zone]...)
+		dst = append(dst, "%25"...)
+
+		return append(dst, escape(host[zone+1:], mode)...)
 	}
 
-	return builder.String()
+	return append(dst, escape(host, mode)...)
 }
 
 // Secure returns if the URI has been upgrade to the SIPS scheme.
@@ -194,16 +862,41 @@ func (sipURI URI) Proto() Protocol {
 	return sipURI.proto
 }
 
+// Scheme returns the name of the scheme, "sip" or "sips", without the
+// trailing colon.
+func (sipURI URI) Scheme() string {
+	return sipURI.proto.String()
+}
+
 // User returns the decoded user portion of the URI.
 func (sipURI URI) User() string {
 	return sipURI.user
 }
 
+// RawUser returns the user portion of the URI still escaped, exactly as it
+// appeared in the parsed input, such as j%40s0n for sip:j%40s0n@host where
+// [URI.User] returns j@s0n. A URI not parsed has its user escaped as written
+// by [URI.String].
+func (sipURI URI) RawUser() string {
+	if sipURI.rawUser != "" {
+		return sipURI.rawUser
+	}
+
+	return escape(sipURI.user, encodeUser)
+}
+
 // Password returns the decoded password portion of the URI.
 func (sipURI URI) Password() string {
 	return sipURI.pass
 }
 
+// Userinfo returns the user and password portions of the URI, along with
+// whether a password was given, which tells sip:alice:@host with an empty
+// password apart from sip:alice@host.
+func (sipURI URI) Userinfo() (string, string, bool) {
+	return sipURI.user, sipURI.pass, sipURI.hadPass || sipURI.pass != ""
+}
+
 // Host returns the decoded host portion of the URI.
 //
 // You may want to use SplitHostPort.
@@ -211,16 +904,112 @@ func (sipURI URI) Host() string {
 	return sipURI.host
 }
 
-// SplitHostPort splits the port from the host portion into.
+// RawHost returns the host portion of the URI, including any port, still
+// escaped exactly as it appeared in the parsed input. A URI not parsed, or
+// whose host was changed by [URI.WithPort] or [URI.Normalize], has its host
+// escaped as written by [URI.String].
+func (sipURI URI) RawHost() string {
+	if sipURI.rawHost != "" {
+		return sipURI.rawHost
+	}
+
+	return string(appendHost(nil, sipURI.host, encodeHost))
+}
+
+// HostName returns the host portion without the port and without the
+// surrounding brackets of an IPv6 reference, suitable for [net.Dial] or
+// comparing addresses.
+func (sipURI URI) HostName() string {
+	host, _, err := sipURI.SplitHostPort()
+	if err != nil {
+		host = sipURI.host
+	}
+
+	if len(host) > 1 && host[0] == '[' && host[len(host)-1] == ']' {
+		return host[1 : len(host)-1]
+	}
+
+	return host
+}
+
+// SplitHostPort splits the port from the host portion into, removing the
+// brackets of an IPv6 reference. A malformed host
+// returns a [MalformedURIError] with the [MalformedHost] cause wrapping the
+// [net.SplitHostPort] error, or [ErrUnbracketedIPv6].
+//
+// The result is computed once when the URI is parsed or constructed.
 func (sipURI URI) SplitHostPort() (string, string, error) {
-	ipv6 := len(sipURI.host) > 0 && sipURI.host[0] == '['
-	colonCount := strings.Count(sipURI.host, ":")
+	if sipURI.hostSplit {
+		return sipURI.hostname, sipURI.port, nil
+	}
+
+	return splitHostPort(sipURI.host)
+}
+
+// splitHost caches the result of [URI.SplitHostPort], returning the error
+// of a malformed host without caching anything.
+func (sipURI *URI) splitHost() error {
+	hostname, port, err := splitHostPort(sipURI.host)
+
+	sipURI.hostname, sipURI.port, sipURI.hostSplit = hostname, port, err == nil
 
-	if (!ipv6 && colonCount > 0) || (ipv6 && (colonCount%2 == 1 || sipURI.host[len(sipURI.host)-1] != ']')) {
-		return net.SplitHostPort(sipURI.host) //nolint:wrapcheck
+	return err
+}
+
+// splitHostPort splits the port from the host. The brackets of an IPv6
+// reference are removed, with a port only split from after the ']' so the
+// colons within the address are never mistaken for it.
+func splitHostPort(host string) (string, string, error) {
+	if len(host) > 0 && host[0] == '[' {
+		if end := strings.IndexByte(host, ']'); end == len(host)-1 {
+			return host[1:end], "", nil
+		}
+
+		return netSplitHostPort(host)
 	}
 
-	return sipURI.host, "", nil
+	switch strings.Count(host, ":") {
+	case 0:
+		return host, "", nil
+	case 1:
+		return netSplitHostPort(host)
+	default:
+		// A hostname or IPv4 address has at most the colon before the port.
+		return "", "", MalformedURIError{Cause: MalformedHost, Err: ErrUnbracketedIPv6}
+	}
+}
+
+// netSplitHostPort calls [net.SplitHostPort] wrapping any error in a
+// [MalformedURIError] with the [MalformedHost] cause.
+func netSplitHostPort(host string) (string, string, error) {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return "", "", MalformedURIError{Cause: MalformedHost, Err: err}
+	}
+
+	return hostname, port, nil
+}
+
+// param returns the first value of the param key, without loading the params
+// when parsed lazily.
+func (sipURI URI) param(key string) string {
+	if lazy, ok := sipURI.params.(*LazyStore); ok {
+		return lazy.peek(key)
+	}
+
+	return sipURI.Params().Get(key)
+}
+
+// HadParams reports if the URI has a params portion, even an empty one. This
+// tells sip:host apart from sip:host; which [URI.String] preserves.
+func (sipURI URI) HadParams() bool {
+	return sipURI.hadParam || !sipURI.Params().Empty()
+}
+
+// HadHeaders reports if the URI has a headers portion, even an empty one. This
+// tells sip:host apart from sip:host? which [URI.String] preserves.
+func (sipURI URI) HadHeaders() bool {
+	return sipURI.hadHeader || !sipURI.Headers().Empty()
 }
 
 // Params returns the decoded params portion of the URI.
@@ -240,3 +1029,26 @@ func (sipURI URI) Headers() KeyValueStore {
 
 	return sipURI.headers
 }
+
+// ParamsAsValues returns a copy of the params as [url.Values]. A flag, such as
+// lr, is mapped to an empty slice.
+func (sipURI URI) ParamsAsValues() url.Values {
+	return valuesOf(sipURI.Params())
+}
+
+// HeadersAsValues returns a copy of the headers as [url.Values].
+func (sipURI URI) HeadersAsValues() url.Values {
+	return valuesOf(sipURI.Headers())
+}
+
+// valuesOf copies the store into a new [url.Values].
+func valuesOf(store KeyValueStore) url.Values {
+	pairs := pairsOf(store)
+	values := make(url.Values, len(pairs))
+
+	for key, vals := range pairs {
+		values[key] = append(make([]string, 0, len(vals)), vals...)
+	}
+
+	return values
+}
@@ -1,7 +1,11 @@
 package sipuri_test
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/percivalalb/sipuri"
@@ -28,6 +32,1418 @@ func TestNew(t *testing.T) {
 	equalF(t, "host:port", uri.Host(), "host mismatch")
 }
 
+func TestNewEncoded(t *testing.T) {
+	t.Parallel()
+
+	equalF(t, "sip:%2541lice@atlanta.com", sipuri.New("%41lice", "atlanta.com").String(), "new escapes decoded input")
+
+	uri, err := sipuri.NewEncoded("%41lice%20smith", "atlanta.com", sipuri.WithTransport("tcp"))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "Alice smith", uri.User(), "user decoded")
+	equalF(t, "sip:Alice%20smith@atlanta.com;transport=tcp", uri.String(), "encoded input not escaped again")
+	equalF(t, sipuri.New("Alice smith", "atlanta.com", sipuri.WithTransport("tcp")), uri, "equivalent to new")
+
+	_, err = sipuri.NewEncoded("%xx", "atlanta.com")
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedUser}) {
+		t.Fatalf("expected malformed user error but got %q", err)
+	}
+
+	_, err = sipuri.NewEncoded("alice", "atlanta.com%2")
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedHost}) {
+		t.Fatalf("expected malformed host error but got %q", err)
+	}
+}
+
+func TestNewStrict(t *testing.T) {
+	t.Parallel()
+
+	uri, err := sipuri.NewStrict("alice", "atlanta.com:5061", sipuri.WithScheme("SIPS"), sipuri.WithTransport("TLS"))
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, sipuri.New("alice", "atlanta.com:5061", sipuri.Secure(), sipuri.WithTransport("tls")), uri, "equivalent to new")
+
+	type test struct {
+		user string
+		host string
+		err  error
+		msg  string
+	}
+
+	tests := []test{
+		{"alice", "", sipuri.MalformedURIError{Cause: sipuri.MissingHost}, "empty host"},
+		{"alice", "atlanta..com", sipuri.MalformedURIError{Cause: sipuri.MalformedHost}, "malformed host"},
+		{"alice", "atlanta.com:http", sipuri.MalformedURIError{Cause: sipuri.MalformedHost}, "malformed port"},
+		{"alice", "::1", sipuri.ErrUnbracketedIPv6, "unbracketed ipv6"},
+		{"alice\r\nbob", "atlanta.com", sipuri.ErrControlCharacter, "control character in user"},
+		{"\xff", "atlanta.com", sipuri.ErrInvalidUTF8, "invalid utf-8 user"},
+	}
+
+	for _, test := range tests {
+		_, err := sipuri.NewStrict(test.user, test.host)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("expected error %q but got %q in %s", test.err, err, test.msg)
+		}
+	}
+
+	_, err = sipuri.NewStrict("", "atlanta.com", sipuri.WithPassword("secret"))
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MissingUser}) {
+		t.Fatalf("expected missing user error but got %q", err)
+	}
+
+	_, err = sipuri.NewStrict("alice", "atlanta.com", sipuri.WithScheme("tel"), sipuri.WithScheme("http"))
+	equalF(t, sipuri.ErrInvalidScheme, err, "unknown scheme")
+
+	_, err = sipuri.NewStrict("alice", "atlanta.com", sipuri.WithParams(sipuri.KeyValuePairs{"user": {"phone"}}))
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedUser}) {
+		t.Fatalf("expected malformed user error but got %q", err)
+	}
+
+	equalF(t, "sip:alice@atlanta.com", sipuri.New("alice", "atlanta.com", sipuri.WithScheme("tel")).String(), "new ignores unknown scheme")
+}
+
+func TestWithTransport(t *testing.T) {
+	t.Parallel()
+
+	params := sipuri.KeyValuePairs{
+		"user":      {"phone"},
+		"transport": {"udp"},
+	}
+
+	uri := sipuri.New(
+		"+1-212-555-1212",
+		"gateway.com",
+		sipuri.WithParams(params),
+		sipuri.WithTransport("TCP"),
+	)
+
+	equalF(t, "tcp", uri.Params().Get("transport"), "transport replaced")
+	equalF(t, "phone", uri.Params().Get("user"), "other params kept")
+	equalF(t, "TCP", uri.Transport(), "transport protocol")
+	equalF(t, "udp", params.Get("transport"), "given params modified")
+
+	uri = sipuri.New("alice", "atlanta.com", sipuri.WithTransport("TLS"))
+
+	equalF(t, "sip:alice@atlanta.com;transport=tls", uri.String(), "transport without other params")
+	equalF(t, "5061", uri.Port(), "default port for tls")
+}
+
+func TestWithPort(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri  string
+		port string
+		str  string
+		msg  string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", "5070", "sip:alice@atlanta.com:5070", "hostname"},
+		{"sip:alice@192.0.2.4;transport=tcp", "5070", "sip:alice@192.0.2.4:5070;transport=tcp", "ipv4"},
+		{"sip:alice@[2001:db8::1]:5060", "5070", "sip:alice@[2001:db8::1]:5070", "ipv6 replacing port"},
+		{"sip:alice@[::1]", "5070", "sip:alice@[::1]:5070", "ipv6"},
+		{"sip:alice@atlanta.com:5060", "", "sip:alice@atlanta.com", "removing port"},
+		{"sip:alice@[2001:db8::1]:5060", "", "sip:alice@[2001:db8::1]", "removing ipv6 port"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			withPort := uri.WithPort(test.port)
+
+			equalF(t, test.str, withPort.String(), "string mismatch in %s", test.msg)
+			equalF(t, test.uri, uri.String(), "original unmodified in %s", test.msg)
+
+			if test.port != "" {
+				equalF(t, test.port, withPort.Port(), "port mismatch in %s", test.msg)
+			}
+		}
+	}
+
+	equalF(t, "sip:alice@[::1]:5070", sipuri.New("alice", "::1", sipuri.WithPort("5070")).String(), "option on new")
+	equalF(t, "sip:alice@atlanta.com", sipuri.New("alice", "atlanta.com:5060", sipuri.WithPort("")).String(), "option removing port")
+}
+
+func TestScheme(t *testing.T) {
+	t.Parallel()
+
+	equalF(t, "sip", sipuri.URI{}.Scheme(), "zero value scheme")
+	equalF(t, "sip", sipuri.New("alice", "atlanta.com").Scheme(), "sip scheme")
+	equalF(t, "sips", sipuri.New("alice", "atlanta.com", sipuri.Secure()).Scheme(), "sips scheme")
+	equalF(t, "sip sips", fmt.Sprint(sipuri.SIP, " ", sipuri.SIPS), "protocol formatted as scheme")
+	equalF(t, "sips", fmt.Sprintf("%v", sipuri.New("alice", "atlanta.com", sipuri.Secure()).Proto()), "proto formatted as scheme")
+
+	type test struct {
+		scheme string
+		proto  sipuri.Protocol
+		ok     bool
+	}
+
+	tests := []test{
+		{"sip", sipuri.SIP, true},
+		{"sips", sipuri.SIPS, true},
+		{"SIP", sipuri.SIP, true},
+		{"SiPs", sipuri.SIPS, true},
+		{"sip:", sipuri.SIP, false},
+		{"tel", sipuri.SIP, false},
+		{"", sipuri.SIP, false},
+	}
+
+	for _, test := range tests {
+		proto, ok := sipuri.ParseScheme(test.scheme)
+
+		equalF(t, test.proto, proto, "protocol mismatch for %q", test.scheme)
+		equalF(t, test.ok, ok, "validity mismatch for %q", test.scheme)
+
+		equalF(t, test.proto, sipuri.New("alice", "atlanta.com", sipuri.WithScheme(test.scheme)).Proto(), "option mismatch for %q", test.scheme)
+		secure := sipuri.New("alice", "atlanta.com", sipuri.Secure(), sipuri.WithScheme(test.scheme))
+		if test.ok {
+			equalF(t, test.proto, secure.Proto(), "option overrides for %q", test.scheme)
+		} else {
+			equalF(t, sipuri.SIPS, secure.Proto(), "unknown scheme unchanged for %q", test.scheme)
+		}
+	}
+}
+
+func TestPort(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri       string
+		transport string
+		port      string
+		msg       string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", "UDP", "5060", "sip default"},
+		{"sips:alice@atlanta.com", "TCP", "5061", "sips default"},
+		{"sip:alice@atlanta.com:5080", "UDP", "5080", "explicit port"},
+		{"sip:alice@atlanta.com;transport=tcp", "TCP", "5060", "sip over tcp"},
+		{"sip:alice@atlanta.com;transport=sctp", "SCTP", "5060", "sip over sctp"},
+		{"sip:alice@atlanta.com;transport=tls", "TLS", "5061", "sip over tls"},
+		{"sip:alice@atlanta.com;transport=ws", "WS", "80", "sip over websocket"},
+		{"sip:alice@atlanta.com;transport=wss", "WSS", "443", "sip over secure websocket"},
+		{"sips:alice@atlanta.com;transport=wss", "WSS", "443", "sips over secure websocket"},
+		{"sip:alice@atlanta.com:8080;transport=ws", "WS", "8080", "websocket with explicit port"},
+		{"sip:alice@atlanta.com;transport=carrier-pigeon", "CARRIER-PIGEON", "", "unknown transport"},
+		{"sip:alice@atlanta.com;transport;transport=TLS", "TLS", "5061", "flag before value"},
+		{"sip:alice@atlanta.com;transpor%74=tcp", "TCP", "5060", "escaped key"},
+		{"sip:alice@atlanta.com;transport=%74cp", "TCP", "5060", "escaped value"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.transport, uri.Transport(), "transport mismatch in %s", test.msg)
+			equalF(t, test.port, uri.Port(), "port mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestPortWith(t *testing.T) {
+	t.Parallel()
+
+	defaults := sipuri.RFCDefaults()
+	defaults.Port[sipuri.SIP]["UDP"] = "6060"
+	defaults.Port[sipuri.SIPS][""] = "6061"
+	defaults.Transport[sipuri.SIPS] = "TLS"
+
+	type test struct {
+		uri       string
+		transport string
+		port      string
+		msg       string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", "UDP", "6060", "custom default port"},
+		{"sip:alice@atlanta.com:5070", "UDP", "5070", "explicit port"},
+		{"sip:alice@atlanta.com;transport=tcp", "TCP", "5060", "rfc port kept"},
+		{"sips:alice@atlanta.com", "TLS", "6061", "custom transport and fallback port"},
+		{"sips:alice@atlanta.com;transport=wss", "WSS", "443", "websocket port kept"},
+		{"sip:alice@atlanta.com;transport=foo", "FOO", "", "unknown transport"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.transport, uri.TransportWith(defaults), "transport mismatch in %s", test.msg)
+			equalF(t, test.port, uri.PortWith(defaults), "port mismatch in %s", test.msg)
+			equalF(t, uri.Port(), uri.PortWith(sipuri.RFCDefaults()), "rfc defaults match port in %s", test.msg)
+			equalF(t, uri.Transport(), uri.TransportWith(sipuri.RFCDefaults()), "rfc defaults match transport in %s", test.msg)
+		}
+	}
+
+	equalF(t, "5060", sipuri.New("alice", "atlanta.com").Port(), "package defaults unmodified")
+}
+func TestHostName(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri      string
+		hostname string
+		msg      string
+	}
+
+	tests := []test{
+		{"sip:user@[2001:db8::1]:5060", "2001:db8::1", "ipv6 with port"},
+		{"sip:user@[::1]", "::1", "ipv6 without port"},
+		{"sip:user@host:5060", "host", "hostname with port"},
+		{"sip:user@host", "host", "hostname without port"},
+		{"sip:user@192.0.2.4:5060", "192.0.2.4", "ipv4 with port"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.hostname, uri.HostName(), "hostname mismatch in %s", test.msg)
+		}
+	}
+
+	equalF(t, "::1", sipuri.New("user", "[::1]").HostName(), "constructed ipv6")
+}
+
+func TestSplitHostPort(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri      string
+		hostname string
+		port     string
+		msg      string
+	}
+
+	tests := []test{
+		{"sip:alice@[::ffff:192.0.2.1]", "::ffff:192.0.2.1", "", "ipv4-mapped ipv6"},
+		{"sip:alice@[::ffff:192.0.2.1]:5060", "::ffff:192.0.2.1", "5060", "ipv4-mapped ipv6 with port"},
+		{"sip:alice@[2001:db8::1]", "2001:db8::1", "", "odd colons"},
+		{"sip:alice@[2001:db8::1]:5070", "2001:db8::1", "5070", "odd colons with port"},
+		{"sip:alice@[::1]", "::1", "", "even colons"},
+		{"sip:alice@[::1]:5070", "::1", "5070", "even colons with port"},
+		{"sip:alice@192.0.2.1:5070", "192.0.2.1", "5070", "ipv4 with port"},
+		{"sip:alice@atlanta.com", "atlanta.com", "", "hostname"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v in %s", err, test.msg)
+			}
+
+			hostname, port, err := uri.SplitHostPort()
+			if err != nil {
+				t.Fatalf("err %v in %s", err, test.msg)
+			}
+
+			equalF(t, test.hostname, hostname, "hostname mismatch in %s", test.msg)
+			equalF(t, test.port, port, "port mismatch in %s", test.msg)
+			equalF(t, test.uri, uri.String(), "reconstructing string %s", test.msg)
+		}
+	}
+}
+
+func TestSplitHostPortError(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		host string
+		err  string
+		msg  string
+	}
+
+	tests := []test{
+		{"[::1", "missing ']' in address", "unterminated ipv6"},
+		{"[::1]:5060:1", "too many colons in address", "too many colons"},
+		{"atlanta.com:", "", "empty port"},
+	}
+
+	for _, test := range tests {
+		_, _, err := sipuri.New("alice", test.host).SplitHostPort()
+
+		if test.err == "" {
+			if err != nil {
+				t.Fatalf("unexpected error %q in %s", err, test.msg)
+			}
+
+			continue
+		}
+
+		if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedHost}) {
+			t.Fatalf("expected malformed host error but got %q in %s", err, test.msg)
+		}
+
+		var addrErr *net.AddrError
+		if !errors.As(err, &addrErr) {
+			t.Fatalf("expected wrapped address error but got %q in %s", err, test.msg)
+		}
+
+		equalF(t, test.err, addrErr.Err, "address error mismatch in %s", test.msg)
+	}
+
+	_, _, err := sipuri.New("alice", "::1").SplitHostPort()
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedHost}) || !errors.Is(err, sipuri.ErrUnbracketedIPv6) {
+		t.Fatalf("expected unbracketed ipv6 error but got %q", err)
+	}
+
+	_, err = sipuri.Parse("sip:alice@[::1]:5060:1")
+	equalF(t, "sip: malformed uri: malformed host: address [::1]:5060:1: too many colons in address", err.Error(), "parse error wraps once")
+}
+
+func TestIPv6Zone(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri      string
+		host     string
+		hostname string
+		port     string
+		msg      string
+	}
+
+	tests := []test{
+		{"sip:alice@[fe80::1%25eth0]", "[fe80::1%eth0]", "fe80::1%eth0", "5060", "zone without port"},
+		{"sip:alice@[fe80::1%25eth0]:5070", "[fe80::1%eth0]:5070", "fe80::1%eth0", "5070", "zone with port"},
+		{"sips:alice@[fe80::1%25en0]:5061;transport=tcp", "[fe80::1%en0]:5061", "fe80::1%en0", "5061", "zone with params"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v in %s", err, test.msg)
+			}
+
+			equalF(t, test.host, uri.Host(), "host mismatch in %s", test.msg)
+			equalF(t, test.hostname, uri.HostName(), "hostname mismatch in %s", test.msg)
+			equalF(t, test.port, uri.Port(), "port mismatch in %s", test.msg)
+			equalF(t, test.uri, uri.String(), "reconstructing string %s", test.msg)
+		}
+
+		if _, err := sipuri.ParseWithOptions(test.uri, sipuri.WithStrictHost()); err != nil {
+			t.Fatalf("unexpected strict host error %v in %s", err, test.msg)
+		}
+	}
+
+	equalF(t, "sip:alice@[fe80::1%25eth0]", sipuri.New("alice", "[fe80::1%eth0]").String(), "constructed zone")
+
+	if _, err := sipuri.ParseWithOptions("sip:alice@[fe80::1%25]", sipuri.WithStrictHost()); err == nil {
+		t.Fatalf("expected strict host error for empty zone")
+	}
+}
+
+func TestUsesTLS(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri string
+		tls bool
+		msg string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com;transport=tls", true, "tls transport"},
+		{"sip:alice@atlanta.com;transport=wss", true, "wss transport"},
+		{"sips:alice@atlanta.com", true, "sips scheme"},
+		{"sips:alice@atlanta.com;transport=tcp", true, "sips scheme with tcp"},
+		{"sip:alice@atlanta.com;transport=tcp", false, "tcp transport"},
+		{"sip:alice@atlanta.com;transport=ws", false, "ws transport"},
+		{"sip:alice@atlanta.com", false, "default transport"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.tls, uri.UsesTLS(), "uses tls mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice:@atlanta.com;transport=tcp?subject=project%20x")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		// Force any lazy store to load so the clone copies the decoded map.
+		_ = uri.Params().Get("transport")
+
+		clone := uri.Clone()
+
+		equalF(t, uri.String(), clone.String(), "clone string representation")
+
+		params, ok := clone.Params().(sipuri.KeyValuePairs)
+		if !ok {
+			params = clone.Params().(*sipuri.LazyStore).KeyValuePairs //nolint:forcetypeassert
+		}
+
+		params["transport"][0] = "udp"
+		params["maddr"] = []string{"239.255.255.1"}
+
+		equalF(t, "tcp", uri.Params().Get("transport"), "original params modified")
+		equalF(t, "", uri.Params().Get("maddr"), "original params modified")
+		equalF(t, "udp", clone.Params().Get("transport"), "clone params not modified")
+	}
+}
+
+func TestWithParamAndHeader(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice@atlanta.com")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		modified := uri.WithParam("transport", "tcp").WithHeader("priority", "urgent")
+
+		equalF(t, "sip:alice@atlanta.com;transport=tcp?priority=urgent", modified.String(), "chained modification")
+		equalF(t, "sip:alice@atlanta.com", uri.String(), "original modified")
+
+		uri, err = parse("sip:alice@atlanta.com;transport=tcp?priority=urgent")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		modified = uri.WithParam("transport", "udp").WithHeader("subject", "project")
+
+		equalF(t, "transport=tcp&transport=udp", modified.Params().Encode(), "param appended")
+		equalF(t, "priority=urgent&subject=project", modified.Headers().Encode(), "header added")
+		equalF(t, "sip:alice@atlanta.com;transport=tcp?priority=urgent", uri.String(), "original modified")
+	}
+}
+
+func TestRequestURI(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice@atlanta.com;method=REGISTER;transport=tcp;maddr=239.255.255.1?to=alice%40atlanta.com")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		requestURI := uri.RequestURI()
+
+		equalF(t, "", requestURI.Params().Get("method"), "method removed")
+		equalF(t, "tcp", requestURI.Params().Get("transport"), "transport kept")
+		equalF(t, "239.255.255.1", requestURI.Maddr(), "maddr kept")
+		equalF(t, false, requestURI.HadHeaders(), "headers removed")
+		equalF(t, "REGISTER", uri.Params().Get("method"), "original modified")
+		equalF(t, true, uri.HadHeaders(), "original modified")
+
+		uri, err = parse("sip:alice@atlanta.com;method=INVITE?subject=x")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "sip:alice@atlanta.com", uri.RequestURI().String(), "only method param")
+
+		uri, err = parse("sip:alice@atlanta.com;METHOD=INVITE;Method=BYE;lr")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "sip:alice@atlanta.com;lr", uri.RequestURI().String(), "mixed case method params")
+	}
+
+	uri, err := sipuri.ParseWithOptions("sip:alice@atlanta.com;b=1;method=INVITE;a=2", sipuri.WithOrderedParams())
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "b=1&a=2", uri.RequestURI().Params().Encode(), "order kept")
+}
+
+func TestMaddr(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri     string
+		maddr   string
+		address string
+		msg     string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com;maddr=239.255.255.1;ttl=15", "239.255.255.1", "239.255.255.1", "maddr present"},
+		{"sip:alice@atlanta.com:5080;ttl=15", "", "atlanta.com", "maddr absent"},
+		{"sip:alice@[::1]:5080", "", "::1", "ipv6 host"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.maddr, uri.Maddr(), "maddr mismatch in %s", test.msg)
+			equalF(t, test.address, uri.ConnectAddress(), "connect address mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestTTL(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri string
+		ttl int
+		ok  bool
+		msg string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com;maddr=239.255.255.1;ttl=15", 15, true, "ttl present"},
+		{"sip:alice@atlanta.com;ttl=0", 0, true, "lower bound"},
+		{"sip:alice@atlanta.com;ttl=255", 255, true, "upper bound"},
+		{"sip:alice@atlanta.com", 0, false, "ttl absent"},
+		{"sip:alice@atlanta.com;ttl", 0, false, "ttl empty"},
+		{"sip:alice@atlanta.com;ttl=256", 0, false, "out of range"},
+		{"sip:alice@atlanta.com;ttl=-1", 0, false, "negative"},
+		{"sip:alice@atlanta.com;ttl=ten", 0, false, "non-numeric"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			ttl, ok := uri.TTL()
+
+			equalF(t, test.ttl, ttl, "ttl mismatch in %s", test.msg)
+			equalF(t, test.ok, ok, "ttl validity mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestLooseRouting(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri string
+		lr  bool
+		msg string
+	}
+
+	tests := []test{
+		{"sip:p1.example.com;lr", true, "valueless flag"},
+		{"sip:p1.example.com;lr=on", true, "flag with value"},
+		{"sip:p1.example.com;transport=tcp", false, "flag absent"},
+		{"sip:p1.example.com", false, "no params"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.lr, uri.LooseRouting(), "loose routing mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestMethod(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri    string
+		method string
+		msg    string
+	}
+
+	tests := []test{
+		{"sip:h;method=register", "REGISTER", "lower case"},
+		{"sip:h;method=Invite", "INVITE", "mixed case"},
+		{"sip:h;method=REFER", "REFER", "upper case"},
+		{"sip:h;method", "", "flag"},
+		{"sip:h", "", "no params"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.method, uri.Method(), "method mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestOutbound(t *testing.T) {
+	t.Parallel()
+
+	const instance = `"<urn:uuid:00000000-0000-1000-8000-AABBCCDDEEFF>"`
+
+	type test struct {
+		uri string
+		ob  bool
+		msg string
+	}
+
+	tests := []test{
+		{"sip:ep1.example.com;lr;ob", true, "valueless flag"},
+		{"sip:ep1.example.com;ob;+sip.instance=" + instance, true, "flag with instance"},
+		{"sip:ep1.example.com;+sip.instance=" + instance, false, "instance only"},
+		{"sip:ep1.example.com", false, "no params"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.ob, uri.Outbound(), "outbound mismatch in %s", test.msg)
+
+			if strings.Contains(test.uri, "+sip.instance") {
+				equalF(t, instance, uri.Params().Get("+sip.instance"), "instance value in %s", test.msg)
+
+				reparsed, err := parse(uri.String())
+				if err != nil {
+					t.Fatalf("err %v", err)
+				}
+
+				equalF(t, instance, reparsed.Params().Get("+sip.instance"), "instance round trip in %s", test.msg)
+				equalF(t, test.ob, reparsed.Outbound(), "outbound round trip in %s", test.msg)
+			}
+		}
+	}
+
+	_, _, params, err := sipuri.ParseNameAddr("<sip:alice@192.0.2.1;ob>;reg-id=1;+sip.instance=" + instance)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, instance, params.Get("+sip.instance"), "instance header-field param")
+}
+
+func TestGlobalNumber(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri     string
+		context string
+		number  string
+		global  bool
+		msg     string
+	}
+
+	tests := []test{
+		{"sip:+1-212-555-1212:1234@gateway.com;user=phone", "", "+1-212-555-1212", true, "global number"},
+		{"sip:+1-212-555-1212;isub=1411@gw.com;user=phone", "", "+1-212-555-1212", true, "global number with params"},
+		{"sip:863-1234;phone-context=+1-914-555@gw.com;user=phone", "+1-914-555", "+1-914-555863-1234", true, "local number with numeric context"},
+		{"sip:7042;Phone-Context=example.com@gw.com;user=phone", "example.com", "", false, "local number with domain context"},
+		{"sip:7042@gw.com;user=phone", "", "", false, "local number without context"},
+		{"sip:+1-212-555-1212@gw.com", "", "", false, "not a phone user"},
+		{"sip:863-1234;phone-context=+1-914-555@gw.com", "", "", false, "context without phone user"},
+		{"sip:863-1234;phone-context=+1-914-555@gw.com;user=Phone", "+1-914-555", "+1-914-555863-1234", true, "mixed case phone user"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			number, global := uri.GlobalNumber()
+
+			equalF(t, test.context, uri.PhoneContext(), "context mismatch in %s", test.msg)
+			equalF(t, test.number, number, "number mismatch in %s", test.msg)
+			equalF(t, test.global, global, "global mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestParsePhoneNumber(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		phone sipuri.PhoneNumber
+		ok    bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:+1-212-555-1212@gateway.com;user=phone", sipuri.PhoneNumber{Raw: "+1-212-555-1212", CountryCode: "1", NationalNumber: "2125551212", IsGlobal: true}, true, "north american number"},
+		{"sip:+44(20)7946.0018;isub=1411@gw.com;user=phone", sipuri.PhoneNumber{Raw: "+44(20)7946.0018", CountryCode: "44", NationalNumber: "2079460018", IsGlobal: true}, true, "two digit country code"},
+		{"sip:+353-1-234-5678@gw.com;user=phone", sipuri.PhoneNumber{Raw: "+353-1-234-5678", CountryCode: "353", NationalNumber: "12345678", IsGlobal: true}, true, "three digit country code"},
+		{"sip:863-1234;phone-context=+1-914-555@gw.com;user=phone", sipuri.PhoneNumber{Raw: "863-1234", CountryCode: "1", NationalNumber: "9145558631234", IsGlobal: true}, true, "local number with numeric context"},
+		{"sip:7042;phone-context=example.com@gw.com;user=phone", sipuri.PhoneNumber{Raw: "7042", NationalNumber: "7042"}, true, "local number with domain context"},
+		{"sip:*69-1@gw.com;user=phone", sipuri.PhoneNumber{Raw: "*69-1", NationalNumber: "*691"}, true, "local number with dtmf digits"},
+		{"sip:+1@gw.com;user=phone", sipuri.PhoneNumber{}, false, "country code only"},
+		{"sip:+1-212-CALL@gw.com;user=phone", sipuri.PhoneNumber{}, false, "invalid global number"},
+		{"sip:alice@gw.com;user=phone", sipuri.PhoneNumber{}, false, "invalid local number"},
+		{"sip:+1-212-555-1212@gw.com", sipuri.PhoneNumber{}, false, "not a phone user"},
+		{"sip:+1-212-555-1212@gw.com;user=pHoNe", sipuri.PhoneNumber{Raw: "+1-212-555-1212", CountryCode: "1", NationalNumber: "2125551212", IsGlobal: true}, true, "mixed case phone user"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			phone, ok := uri.ParsePhoneNumber()
+
+			equalF(t, test.phone, phone, "phone number mismatch in %s", test.msg)
+			equalF(t, test.ok, ok, "validity mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestTargetCause(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:vm@host;target=sip%3Aalice%40atlanta.com;cause=302")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		target, err := uri.Target()
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "sip:alice@atlanta.com", target.String(), "target parsed")
+		equalF(t, "alice", target.User(), "target user")
+
+		cause, ok := uri.Cause()
+		equalF(t, 302, cause, "cause")
+		equalF(t, true, ok, "cause present")
+
+		uri, err = parse("sip:vm@host;target=sip%3Abob%40biloxi.com%3Btarget%3Dsip%253Aalice%2540atlanta.com")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		target, err = uri.Target()
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		nested, err := target.Target()
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "sip:alice@atlanta.com", nested.String(), "nested target")
+
+		uri, err = parse("sip:vm@host;target=tel%3A%2B1-212-555-1212;cause=abc")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		if _, err := uri.Target(); !errors.Is(err, sipuri.ErrInvalidScheme) {
+			t.Fatalf("expected invalid scheme error but got %q", err)
+		}
+
+		_, ok = uri.Cause()
+		equalF(t, false, ok, "non-numeric cause")
+
+		uri, err = parse("sip:vm@host;cause=99")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		target, err = uri.Target()
+		equalF(t, (*sipuri.URI)(nil), target, "no target")
+		equalF(t, nil, err, "no error without target")
+
+		_, ok = uri.Cause()
+		equalF(t, false, ok, "cause out of range")
+	}
+}
+
+func TestGRUU(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		value string
+		gruu  bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:bob@example.com;gr", "", true, "valueless flag"},
+		{"sip:bob@example.com;gr=urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6", "urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6", true, "public gruu value"},
+		{"sip:bob@example.com;gr=", "", true, "empty value"},
+		{"sip:bob@example.com;transport=tcp", "", false, "gr absent"},
+		{"sip:bob@example.com", "", false, "no params"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			value, gruu := uri.GRUU()
+
+			equalF(t, test.value, value, "value mismatch in %s", test.msg)
+			equalF(t, test.gruu, gruu, "gruu mismatch in %s", test.msg)
+		}
+	}
+}
+
+func TestRawComponents(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri     string
+		rawUser string
+		user    string
+		rawHost string
+		host    string
+		msg     string
+	}
+
+	tests := []test{
+		{"sip:j%40s0n@host", "j%40s0n", "j@s0n", "host", "host", "escaped user"},
+		{"sip:j%40s0n:pass@host", "j%40s0n", "j@s0n", "host", "host", "escaped user with password"},
+		{"sip:%61lice@atl%61nta.com:5060", "%61lice", "alice", "atl%61nta.com:5060", "atlanta.com:5060", "unnecessary escapes"},
+		{"sip:alice@atlanta.com", "alice", "alice", "atlanta.com", "atlanta.com", "no escapes"},
+		{"sip:atlanta.com;transport=tcp", "", "", "atlanta.com", "atlanta.com", "no user"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.rawUser, uri.RawUser(), "raw user in %s", test.msg)
+			equalF(t, test.user, uri.User(), "user in %s", test.msg)
+			equalF(t, test.rawHost, uri.RawHost(), "raw host in %s", test.msg)
+			equalF(t, test.host, uri.Host(), "host in %s", test.msg)
+		}
+	}
+
+	uri := sipuri.New("j@s0n", "atlanta.com")
+	equalF(t, "j%40s0n", uri.RawUser(), "constructed user escaped")
+	equalF(t, "atlanta.com", uri.RawHost(), "constructed host")
+
+	parsed, err := sipuri.Parse("sip:alice@AtLanTa.CoM")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "AtLanTa.CoM", parsed.RawHost(), "raw host keeps case")
+	equalF(t, "atlanta.com", parsed.Normalize().RawHost(), "normalized host")
+	equalF(t, "AtLanTa.CoM:5070", parsed.WithPort("5070").RawHost(), "port changed")
+	equalF(t, "AtLanTa.CoM", parsed.RawHost(), "original unchanged")
+}
+
+func TestUserinfo(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri     string
+		user    string
+		pass    string
+		hasPass bool
+		msg     string
+	}
+
+	tests := []test{
+		{"sip:alice:@atlanta.com", "alice", "", true, "empty password"},
+		{"sip:alice@atlanta.com", "alice", "", false, "no password"},
+		{"sip:alice:secretword@atlanta.com", "alice", "secretword", true, "password"},
+		{"sip:atlanta.com", "", "", false, "no userinfo"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			user, pass, hasPass := uri.Userinfo()
+
+			equalF(t, test.user, user, "user mismatch in %s", test.msg)
+			equalF(t, test.pass, pass, "password mismatch in %s", test.msg)
+			equalF(t, test.hasPass, hasPass, "has password mismatch in %s", test.msg)
+		}
+	}
+
+	_, _, hasPass := sipuri.New("alice", "atlanta.com", sipuri.WithPassword("secretword")).Userinfo()
+	equalF(t, true, hasPass, "password option")
+}
+
+func TestHadParamsAndHeaders(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri     string
+		params  bool
+		headers bool
+		msg     string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", false, false, "neither"},
+		{"sip:alice@atlanta.com;", true, false, "empty params"},
+		{"sip:alice@atlanta.com?", false, true, "empty headers"},
+		{"sip:alice@atlanta.com;?", true, true, "both empty"},
+		{"sip:alice@atlanta.com;lr?subject=x", true, true, "both present"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.params, uri.HadParams(), "params mismatch in %s", test.msg)
+			equalF(t, test.headers, uri.HadHeaders(), "headers mismatch in %s", test.msg)
+		}
+	}
+
+	uri := sipuri.New("alice", "atlanta.com", sipuri.WithTransport("tcp"))
+	equalF(t, true, uri.HadParams(), "constructed with params")
+	equalF(t, false, uri.HadHeaders(), "constructed without headers")
+}
+
+func TestAsValues(t *testing.T) {
+	t.Parallel()
+
+	for _, parse := range parseFuncs {
+		uri, err := parse("sip:alice@atlanta.com;x=1;lr;x=2;e=?to=bob&subject=")
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		params := uri.ParamsAsValues()
+		equalF(t, url.Values{"x": {"1", "2"}, "lr": {}, "e": {""}}, params, "params converted")
+
+		params.Add("x", "3")
+		params["lr"] = append(params["lr"], "on")
+		equalF(t, []string{"1", "2"}, uri.Params().Values("x"), "params copied")
+		equalF(t, 0, len(uri.Params().Values("lr")), "flag copied")
+
+		equalF(t, url.Values{"to": {"bob"}, "subject": {""}}, uri.HeadersAsValues(), "headers converted")
+		equalF(t, url.Values{}, sipuri.New("", "atlanta.com").HeadersAsValues(), "empty store")
+	}
+
+	values := url.Values{"x": {"1", "2"}, "lr": {}}
+	uri := sipuri.New("alice", "atlanta.com", sipuri.WithParamsFromValues(values))
+
+	values.Set("x", "3")
+	equalF(t, "sip:alice@atlanta.com;lr;x=1;x=2", uri.String(), "values copied")
+	equalF(t, true, uri.LooseRouting(), "flag kept")
+}
+
+func TestCloneKeyValuePairs(t *testing.T) {
+	t.Parallel()
+
+	equalF(t, sipuri.KeyValuePairs(nil), sipuri.KeyValuePairs(nil).Clone(), "nil map clone")
+
+	pairs := sipuri.KeyValuePairs{"dog": {"bark", "woof"}}
+	clone := pairs.Clone()
+	clone["dog"][1] = "growl"
+
+	equalF(t, []string{"bark", "woof"}, pairs["dog"], "original values modified")
+}
+
+func TestRedacted(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri      string
+		redacted string
+		msg      string
+	}
+
+	tests := []test{
+		{"sip:alice:secret@atlanta.com;transport=tcp", "sip:alice:xxxxx@atlanta.com;transport=tcp", "password"},
+		{"sip:alice:@atlanta.com", "sip:alice:xxxxx@atlanta.com", "empty password"},
+		{"sip:alice@atlanta.com;transport=tcp?subject=x", "sip:alice@atlanta.com;transport=tcp?subject=x", "no password"},
+		{"sip:alice@atlanta.com;?", "sip:alice@atlanta.com;?", "no password quirks"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.redacted, uri.Redacted(), "redacted mismatch in %s", test.msg)
+		}
+	}
+
+	uri, err := sipuri.ParseWithOptions("sip:alice:secret@atlanta.com", sipuri.WithRaw())
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "sip:alice:xxxxx@atlanta.com", uri.Redacted(), "raw input redacted")
+	equalF(t, "sip:alice:secret@atlanta.com", uri.String(), "original unmodified")
+
+	equalF(t, "sip:bob@biloxi.com", sipuri.New("bob", "biloxi.com").Redacted(), "constructed without password")
+}
+
+func TestStringParamSeparator(t *testing.T) {
+	t.Parallel()
+
+	uri := sipuri.New("alice", "atlanta.com",
+		sipuri.WithParams(sipuri.Pairs("transport", "tcp", "user", "phone")),
+		sipuri.WithHeaders(sipuri.Pairs("subject", "x", "priority", "urgent")),
+	)
+
+	equalF(t, "sip:alice@atlanta.com;transport=tcp;user=phone?priority=urgent&subject=x", uri.String(), "params joined with ;")
+	equalF(t, "transport=tcp&user=phone", uri.Params().Encode(), "encode joins with &")
+	equalF(t, "transport=tcp;user=phone", uri.Params().EncodeSep(";"), "encode sep joins with ;")
+
+	for _, parse := range parseFuncs {
+		parsed, err := parse(uri.String())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, "tcp", parsed.Params().Get("transport"), "transport round trip")
+		equalF(t, "phone", parsed.Params().Get("user"), "user round trip")
+		equalF(t, "transport=tcp;user=phone", parsed.Params().EncodeSep(";"), "parsed params joined with ;")
+		equalF(t, "sip:alice@atlanta.com;transport=tcp;ttl=1;user=phone?priority=urgent&subject=x", parsed.WithParam("ttl", "1").String(), "modified params joined with ;")
+	}
+}
+
+func TestStringMultiParamRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"sip:h;transport=tcp;user=phone",
+		"sip:alice@atlanta.com;lr;maddr=239.255.255.1;transport=udp;ttl=15",
+		"sip:alice@atlanta.com;transport=tcp;user=phone?priority=urgent&subject=x",
+	} {
+		for _, parse := range parseFuncs {
+			uri, err := parse(input)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, input, uri.String(), "round trip of %q", input)
+		}
+	}
+
+	const unsorted = "sip:h;user=phone;transport=tcp?subject=x&priority=urgent"
+
+	uri, err := sipuri.Parse(unsorted)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "sip:h;transport=tcp;user=phone?priority=urgent&subject=x", uri.String(), "sorted by key")
+
+	uri, err = sipuri.ParseLazy(unsorted)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, unsorted, uri.String(), "lazy keeps input order")
+
+	uri, err = sipuri.ParseWithOptions(unsorted, sipuri.WithOrderedParams(), sipuri.WithOrderedHeaders())
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, unsorted, uri.String(), "ordered keeps input order")
+	equalF(t, "sip:h;user=phone;transport=tcp;lr=?subject=x&priority=urgent", uri.WithParam("lr", "").String(), "ordered keeps order when modified")
+}
+
+func TestMinimal(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri     string
+		minimal string
+		msg     string
+	}
+
+	tests := []test{
+		{"sip:h;", "sip:h", "empty params"},
+		{"sip:h?", "sip:h", "empty headers"},
+		{"sip:h;?", "sip:h", "empty params and headers"},
+		{"sip:alice@atlanta.com;lr?", "sip:alice@atlanta.com;lr", "params kept"},
+		{"sip:alice@atlanta.com;?subject=x", "sip:alice@atlanta.com?subject=x", "headers kept"},
+		{"sip:alice@atlanta.com", "sip:alice@atlanta.com", "already minimal"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.minimal, uri.Minimal(), "minimal mismatch in %s", test.msg)
+			equalF(t, test.uri, uri.String(), "original modified in %s", test.msg)
+		}
+	}
+
+	uri, err := sipuri.ParseWithOptions("sip:h;", sipuri.WithRaw())
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, "sip:h", uri.Minimal(), "raw input minimized")
+}
+
+func TestStringEscaping(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri sipuri.URI
+		str string
+		msg string
+	}
+
+	tests := []test{
+		{sipuri.New("+1 2 3 (3)", "host"), "sip:+1%202%203%20%283%29@host", "user with spaces and parentheses"},
+		{sipuri.New("alice", "host", sipuri.WithPassword("p@ss (word)")), "sip:alice:p%40ss%20%28word%29@host", "password"},
+		{sipuri.New("alice;day=tuesday?x/y:z", "host"), "sip:alice;day=tuesday?x/y%3Az@host", "user with user-unreserved"},
+		{sipuri.New("alice", "host", sipuri.WithPassword("a;b?c/d&e=f")), "sip:alice:a%3Bb%3Fc%2Fd&e=f@host", "password with reserved"},
+		{sipuri.New("", "0! 0000000"), "sip:0%21%200000000", "host with sub-delims and space"},
+		{sipuri.New("alice", "[fe80::1%eth 0]"), "sip:alice@[fe80::1%25eth%200]", "ipv6 zone with space"},
+		{sipuri.New("alice", `ho<st>"x"`), "sip:alice@ho%3Cst%3E%22x%22", "host with message delimiters"},
+		{sipuri.New("alice", "ho!st$&'()*+,;=:5060"), "sip:alice@ho%21st%24%26%27%28%29%2A%2B%2C%3B%3D:5060", "host with sub-delims"},
+		{sipuri.New("alice", "ho;st"), "sip:alice@ho%3Bst", "host with param delimiter"},
+	}
+
+	for _, test := range tests {
+		equalF(t, test.str, test.uri.String(), "string mismatch in %s", test.msg)
+
+		for _, parse := range parseFuncs {
+			parsed, err := parse(test.str)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.uri.Host(), parsed.Host(), "host round trip in %s", test.msg)
+			equalF(t, test.str, parsed.String(), "string round trip in %s", test.msg)
+		}
+	}
+
+	lenient := sipuri.New("alice", "ho!st$&'()*+,=:5060")
+	equalF(t, "sip:alice@ho!st$&'()*+,=:5060", lenient.Lenient(), "lenient host with sub-delims")
+	equalF(t, "sip:alice@ho%3Cst%3E", sipuri.New("alice", "ho<st>").Lenient(), "lenient host with message delimiters")
+}
+
+func TestUserinfoRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri  string
+		user string
+		pass string
+		msg  string
+	}
+
+	tests := []test{
+		{"sip:alice;day=tuesday@atlanta.com", "alice;day=tuesday", "", "user with params"},
+		{"sip:alice?x/y;z=1@atlanta.com;transport=tcp", "alice?x/y;z=1", "", "user with ? and /"},
+		{"sip:alice%3Ax@atlanta.com", "alice:x", "", "user with escaped colon"},
+		{"sip:alice:a%20b%40c@atlanta.com", "alice", "a b@c", "password with escaped space and @"},
+		{"sip:alice:a%3Bb%3Fc%2Fd@atlanta.com", "alice", "a;b?c/d", "escaped password"},
+	}
+
+	for _, test := range tests {
+		for _, parse := range parseFuncs {
+			uri, err := parse(test.uri)
+			if err != nil {
+				t.Fatalf("err %v", err)
+			}
+
+			equalF(t, test.user, uri.User(), "user mismatch in %s", test.msg)
+			equalF(t, test.pass, uri.Password(), "password mismatch in %s", test.msg)
+			equalF(t, test.uri, uri.String(), "string mismatch in %s", test.msg)
+		}
+	}
+
+	_, err := sipuri.Parse("sip:alice:a%xx@atlanta.com")
+	if !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedUser}) {
+		t.Fatalf("expected malformed user error but got %q", err)
+	}
+
+	offsetF(t, 11, err, "malformed password offset")
+	equalF(t, false, sipuri.Valid("sip:alice:a%xx@atlanta.com"), "valid checks the password")
+}
+
+func TestWithUserParam(t *testing.T) {
+	t.Parallel()
+
+	uri := sipuri.New("alice", "atlanta.com", sipuri.WithUserParam("day", "tuesday"))
+
+	equalF(t, "alice;day=tuesday", uri.User(), "param part of user")
+	equalF(t, true, uri.Params().Empty(), "no uri params")
+	equalF(t, "sip:alice;day=tuesday@atlanta.com", uri.String(), "semicolon not escaped")
+	equalF(t, true, uri.Equal(sipuri.New("alice;day=tuesday", "atlanta.com")), "same as verbatim user")
+
+	uri = sipuri.New("+1-212-555-1212", "gateway.com",
+		sipuri.WithUserParam("isub", "1411"), sipuri.WithUserParam("x", ""), sipuri.WithParams(sipuri.Pairs("user", "phone")))
+	equalF(t, "sip:+1-212-555-1212;isub=1411;x@gateway.com;user=phone", uri.String(), "several user params")
+
+	for _, parse := range parseFuncs {
+		parsed, err := parse(uri.String())
+		if err != nil {
+			t.Fatalf("err %v", err)
+		}
+
+		equalF(t, uri.User(), parsed.User(), "user round trip")
+		equalF(t, true, uri.Equal(*parsed), "uri round trip")
+	}
+}
+
+func TestAppendString(t *testing.T) {
+	t.Parallel()
+
+	uri := sipuri.New("alice", "atlanta.com", sipuri.Secure())
+
+	buf := []byte("Contact: ")
+	buf = uri.AppendString(buf)
+
+	equalF(t, "Contact: sips:alice@atlanta.com", string(buf), "appended string")
+}
+
+func TestWriteTo(t *testing.T) {
+	t.Parallel()
+
+	uri, err := sipuri.Parse("sips:alice:@atlanta.com;transport=tcp?subject=project%20x")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("Contact: ")
+
+	n, err := uri.WriteTo(&builder)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+
+	equalF(t, int64(len(uri.String())), n, "byte count")
+	equalF(t, "Contact: "+uri.String(), builder.String(), "written bytes")
+
+	errWrite := errors.New("write failed")
+
+	n, err = uri.WriteTo(failingWriter{n: 4, err: errWrite})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("expected write error but got %q", err)
+	}
+
+	equalF(t, int64(4), n, "partial byte count")
+}
+
+// failingWriter reports writing n bytes then returns err.
+type failingWriter struct {
+	n   int
+	err error
+}
+
+func (w failingWriter) Write(_ []byte) (int, error) {
+	return w.n, w.err
+}
+
+func BenchmarkString(b *testing.B) {
+	uri := sipuri.New("alice", "atlanta.com", sipuri.WithParams(sipuri.KeyValuePairs{
+		"transport": {"tcp"},
+	}))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = uri.String()
+	}
+}
+
+func BenchmarkAppendString(b *testing.B) {
+	uri := sipuri.New("alice", "atlanta.com", sipuri.WithParams(sipuri.KeyValuePairs{
+		"transport": {"tcp"},
+	}))
+	buf := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		buf = uri.AppendString(buf[:0])
+	}
+}
+
+func BenchmarkTransportLazy(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		uri, err := sipuri.ParseLazy("sip:alice@atlanta.com;maddr=239.255.255.1;ttl=15;transport=tcp;lr")
+		if err != nil {
+			b.Fatalf("err %v", err)
+		}
+
+		_ = uri.Transport()
+	}
+}
+
+func BenchmarkPort(b *testing.B) {
+	uri, err := sipuri.Parse("sip:alice@[2001:db8::2:1]:5070;transport=tcp")
+	if err != nil {
+		b.Fatalf("err %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = uri.Port()
+	}
+}
+
 func ExampleNew() {
 	sipURI := sipuri.New(
 		"user",
@@ -0,0 +1,172 @@
+package sipuri
+
+import (
+	"errors"
+	"strings"
+)
+
+// TelProtocol is the scheme of a telephone URI.
+//
+// From https://www.rfc-editor.org/rfc/rfc3966
+const TelProtocol = "tel:"
+
+// ErrMissingPhoneContext is returned when a local number is given without
+// the phone-context parameter required to make it globally unique.
+var ErrMissingPhoneContext = errors.New("tel: local number missing phone-context")
+
+// TelURI stores the components of that make up a tel URI.
+//
+// A general tel uri looks like:
+//
+//	tel:telephone-subscriber;phone-context=descriptor;parameters
+type TelURI struct {
+	number  string
+	context string
+	params  KeyValueStore
+}
+
+// ParseTel parses the given tel uri.
+//
+// The telephone-subscriber is reported as the user in any [MalformedURIError].
+func ParseTel(uri string) (*TelURI, error) {
+	if !hasScheme(uri, TelProtocol) {
+		return nil, ErrInvalidScheme
+	}
+
+	rawNumber, params, _ := strings.Cut(uri[len(TelProtocol):], ";")
+	paramsAt := len(TelProtocol) + len(rawNumber) + 1
+
+	if rawNumber == "" {
+		return nil, MalformedURIError{Cause: MissingUser, Offset: len(TelProtocol)}
+	}
+
+	number, pos, err := unescape(rawNumber)
+	if err != nil {
+		return nil, MalformedURIError{Cause: MalformedUser, Err: err, Offset: len(TelProtocol) + pos}
+	}
+
+	global := number[0] == '+'
+	if !validTelNumber(number, global) {
+		return nil, MalformedURIError{Cause: MalformedUser, Offset: len(TelProtocol)}
+	}
+
+	telURI := TelURI{number: number, params: EmptyStore{}}
+
+	if params != "" {
+		pairs, pos, err := decodeURLValues(params, ";")
+		if err != nil {
+			return nil, MalformedURIError{Cause: MalformedParams, Err: err, Offset: paramsAt + pos}
+		}
+
+		telURI.context = pairs.Get("phone-context")
+		delete(pairs, "phone-context")
+
+		if telURI.context != "" && !validTelContext(telURI.context) {
+			return nil, MalformedURIError{Cause: MalformedParams, Offset: paramsAt}
+		}
+
+		if len(pairs) > 0 {
+			telURI.params = pairs
+		}
+	}
+
+	// §5.1.5 "The 'phone-context' parameter MUST be included for local numbers"
+	if !global && telURI.context == "" {
+		return nil, MalformedURIError{Cause: MalformedParams, Err: ErrMissingPhoneContext, Offset: paramsAt}
+	}
+
+	return &telURI, nil
+}
+
+// validTelNumber checks the number is made up of phone digits and visual
+// separators per §3 of RFC 3966. Global numbers are restricted to decimal
+// digits where as local numbers may also include HEXDIG, '*' and '#'.
+func validTelNumber(number string, global bool) bool {
+	if global {
+		number = number[1:]
+	}
+
+	var hasDigit bool
+
+	for i := 0; i < len(number); i++ {
+		switch c := number[i]; {
+		case '0' <= c && c <= '9':
+			hasDigit = true
+		case c == '-' || c == '.' || c == '(' || c == ')': // visual-separator
+		case !global && ('A' <= c && c <= 'F' || 'a' <= c && c <= 'f' || c == '*' || c == '#'):
+			hasDigit = true
+		default:
+			return false
+		}
+	}
+
+	return hasDigit
+}
+
+// validTelContext checks the phone-context descriptor is either a
+// global number or a domain name.
+func validTelContext(context string) bool {
+	if context[0] == '+' {
+		return validTelNumber(context, true)
+	}
+
+	for i := 0; i < len(context); i++ {
+		c := context[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Subscriber returns the decoded telephone-subscriber portion of the URI
+// including any visual separators.
+func (telURI TelURI) Subscriber() string {
+	return telURI.number
+}
+
+// Global returns if the subscriber is a global number, that is it starts
+// with a '+'.
+func (telURI TelURI) Global() bool {
+	return len(telURI.number) > 0 && telURI.number[0] == '+'
+}
+
+// Context returns the phone-context parameter of the URI. Empty string otherwise.
+func (telURI TelURI) Context() string {
+	return telURI.context
+}
+
+// Params returns the decoded params portion of the URI excluding the
+// phone-context.
+func (telURI TelURI) Params() KeyValueStore {
+	if telURI.params == nil {
+		return EmptyStore{}
+	}
+
+	return telURI.params
+}
+
+// String rebuilds the string representation of the URI.
+//
+// The phone-context is always written as the first parameter.
+func (telURI TelURI) String() string {
+	var builder strings.Builder
+
+	builder.WriteString(TelProtocol)
+
+	// Only '#' of the subscriber characters must be escaped.
+	builder.WriteString(strings.ReplaceAll(telURI.number, "#", "%23"))
+
+	if telURI.context != "" {
+		builder.WriteString(";phone-context=")
+		builder.WriteString(telURI.context)
+	}
+
+	if params, ok := telURI.Params().(KeyValuePairs); ok && !params.Empty() {
+		builder.WriteByte(';')
+		builder.WriteString(encodeURLValues(params, ";"))
+	}
+
+	return builder.String()
+}
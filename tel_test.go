@@ -0,0 +1,89 @@
+package sipuri_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestParseTel(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri     string
+		number  string
+		context string
+		global  bool
+		params  string
+		msg     string
+	}
+
+	tests := []test{
+		{"tel:+1-212-555-1212;phone-context=example.com", "+1-212-555-1212", "example.com", true, "", "global number with context"},
+		{"tel:+1-201-555-0123", "+1-201-555-0123", "", true, "", "RFC example #1"},
+		{"tel:7042;phone-context=example.com", "7042", "example.com", false, "", "RFC example #2"},
+		{"tel:863-1234;phone-context=+1-914-555", "863-1234", "+1-914-555", false, "", "RFC example #3"},
+		{"tel:+44.(20).7946.0018", "+44.(20).7946.0018", "", true, "", "visual separators"},
+		{"tel:*21%23;phone-context=example.com", "*21#", "example.com", false, "", "local dtmf digits"},
+		{"tel:+1-201-555-0123;ext=1234", "+1-201-555-0123", "", true, "ext=1234", "extension param"},
+	}
+
+	for _, test := range tests {
+		telURI, err := sipuri.ParseTel(test.uri)
+		if err != nil {
+			t.Fatalf(`failed to parse tel URI %q, %v error`, test.uri, err)
+		}
+
+		equalF(t, test.number, telURI.Subscriber(), "subscriber mismatch in %s", test.msg)
+		equalF(t, test.context, telURI.Context(), "context mismatch in %s", test.msg)
+		equalF(t, test.global, telURI.Global(), "global mismatch in %s", test.msg)
+		equalF(t, test.params, telURI.Params().Encode(), "param mismatch in %s", test.msg)
+
+		equalF(t, test.uri, telURI.String(), "reconstructing string %s", test.msg)
+	}
+
+	telURI, err := sipuri.ParseTel("TEL:+1-201-555-0123")
+	if err != nil {
+		t.Fatalf(`failed to parse uppercase tel URI, %v error`, err)
+	}
+
+	equalF(t, "tel:+1-201-555-0123", telURI.String(), "uppercase scheme")
+}
+
+func TestParseTelError(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri string
+		err error
+		msg string
+	}
+
+	tests := []test{
+		{"sip:+1-201-555-0123", sipuri.ErrInvalidScheme, "sip scheme"},
+		{"tel:", sipuri.MalformedURIError{Cause: sipuri.MissingUser}, "no subscriber present"},
+		{"tel:;phone-context=example.com", sipuri.MalformedURIError{Cause: sipuri.MissingUser}, "no subscriber present"},
+		{"tel:+", sipuri.MalformedURIError{Cause: sipuri.MalformedUser}, "global number without digits"},
+		{"tel:+1-20A", sipuri.MalformedURIError{Cause: sipuri.MalformedUser}, "hex digit in global number"},
+		{"tel:555 0123;phone-context=example.com", sipuri.MalformedURIError{Cause: sipuri.MalformedUser}, "space in number"},
+		{"tel:%xx", sipuri.MalformedURIError{Cause: sipuri.MalformedUser}, "malformed url encoded number"},
+		{"tel:7042", sipuri.ErrMissingPhoneContext, "local number without context"},
+		{"tel:7042;phone-context=exa_mple", sipuri.MalformedURIError{Cause: sipuri.MalformedParams}, "malformed context"},
+		{"tel:+1-201-555-0123;%xx", sipuri.MalformedURIError{Cause: sipuri.MalformedParams}, "malformed url encoded params"},
+	}
+
+	for _, test := range tests {
+		nul, err := sipuri.ParseTel(test.uri)
+
+		if !errors.Is(err, test.err) {
+			t.Fatalf(`expected error %q but got %q in %s`, test.err, err, test.msg)
+		}
+
+		equalF(t, (*sipuri.TelURI)(nil), nul, "nil received %s", test.msg)
+	}
+
+	_, err := sipuri.ParseTel("tel:+1-201-555-0123;ext=%xx")
+	equalF(t, `sip: malformed uri: malformed params: sip: invalid URL escape "%xx"`, err.Error(), "offset in error")
+	offsetF(t, 24, err, "offset in error")
+}
@@ -0,0 +1,352 @@
+package sipuri
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is wrapped by the [MalformedURIError] returned when a decoded
+// component is not valid UTF-8 under [WithUTF8Validation].
+var ErrInvalidUTF8 = errors.New("sip: invalid UTF-8")
+
+// ErrControlCharacter is wrapped by the [MalformedURIError] returned when a
+// decoded component contains a control character or space under
+// [WithControlCharValidation].
+var ErrControlCharacter = errors.New("sip: control character or space")
+
+// strictOptions returns the validation options applied by [ParseStrict].
+func strictOptions() []parseOption {
+	return []parseOption{
+		WithStrictHost(),
+		WithPhoneUserValidation(),
+		WithUTF8Validation(),
+		WithControlCharValidation(),
+		WithSingleValuedParams(),
+		WithTransportValidation(),
+	}
+}
+
+// WithPhoneUserValidation checks the user is a valid telephone-subscriber when
+// the URI has the user=phone param, returning a [MalformedURIError] with the
+// [MalformedUser] cause otherwise.
+//
+// The number, ignoring any parameters after a ';', may contain digits and the
+// visual separators '-', '.', '(' and ')'. Global numbers start with a '+'
+// where as local numbers may also contain the dtmf-digits '*', '#' and A-F.
+func WithPhoneUserValidation() parseOption {
+	return func(p *parser) {
+		p.phoneUser = true
+	}
+}
+
+// WithStrictHost checks the host is a well-formed hostname, IPv4 address or
+// bracketed IPv6 reference, with an optional numeric port, returning a
+// [MalformedURIError] with the [MalformedHost] cause otherwise. An IPv6
+// reference may have a non-empty zone, such as [fe80::1%25eth0]. A host of
+// four numeric labels must be a valid IPv4 address, so 999.1.1.1 is rejected.
+//
+// Hostname labels must be at most 63 alphanumeric characters or '-', not
+// starting or ending with a '-'. A single trailing '.' is allowed.
+func WithStrictHost() parseOption {
+	return func(p *parser) {
+		p.strictHost = true
+	}
+}
+
+// WithUTF8Validation checks each component is valid UTF-8 once decoded, so
+// an escape such as %FF is rejected, returning a [MalformedURIError] wrapping
+// [ErrInvalidUTF8] with the cause of the first invalid component.
+//
+// The params and headers are decoded to be checked, so are no longer lazily
+// loaded.
+func WithUTF8Validation() parseOption {
+	return func(p *parser) {
+		p.utf8 = true
+	}
+}
+
+// WithControlCharValidation checks no component contains a control character,
+// such as CR or LF, or a space once decoded, returning a [MalformedURIError]
+// wrapping [ErrControlCharacter] with the cause of the first invalid
+// component. This guards against a URI splitting the SIP message it is later
+// written into.
+//
+// The params and headers are decoded to be checked, so are no longer lazily
+// loaded.
+func WithControlCharValidation() parseOption {
+	return func(p *parser) {
+		p.controlChars = true
+	}
+}
+
+// WithoutPassword rejects a URI with a password, even an empty one, returning
+// a [MalformedURIError] with the [DeprecatedPassword] cause. §19.1.1 says
+// "The use of passwords in the userinfo is NOT RECOMMENDED".
+//
+// Unlike the other validation options it is not applied by [ParseStrict].
+func WithoutPassword() parseOption {
+	return func(p *parser) {
+		p.noPassword = true
+	}
+}
+
+// WithTransportValidation rejects a sips URI whose transport param names an
+// insecure transport, such as sips:alice@atlanta.com;transport=udp, returning
+// a [MalformedURIError] with the [TransportSchemeMismatch] cause. §26.2.2
+// requires TLS for every hop of a sips URI.
+//
+// The tcp transport is accepted, as RFC 5630 §3.1.3 deprecates transport=tls
+// in favour of a sips URI with transport=tcp meaning TLS over TCP.
+func WithTransportValidation() parseOption {
+	return func(p *parser) {
+		p.transport = true
+	}
+}
+
+// insecureTransport reports if the transport can not carry TLS.
+func insecureTransport(transport string) bool {
+	for _, insecure := range [...]string{"udp", "sctp", "ws"} {
+		if strings.EqualFold(transport, insecure) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SingleValuedParams are the params checked by [WithSingleValuedParams] to
+// appear at most once, as a URI can not for example use two transports.
+//
+//nolint:gochecknoglobals
+var SingleValuedParams = []string{"transport", "user", "method", "ttl", "maddr", "lr"}
+
+// WithSingleValuedParams rejects a URI with any of the [SingleValuedParams]
+// appearing more than once, even with the same value, returning a
+// [MalformedURIError] with the [MalformedParams] cause. The param names are
+// matched case-insensitively. Other params may still repeat.
+func WithSingleValuedParams() parseOption {
+	return func(p *parser) {
+		p.singleValued = true
+	}
+}
+
+// repeatedParam returns the index of the first of the [SingleValuedParams] to
+// appear again in the encoded params.
+func repeatedParam(params string) (int, bool) {
+	var seen []string
+
+	offset := 0
+
+	for _, pair := range strings.Split(params, ";") {
+		rawKey, _, _ := strings.Cut(pair, "=")
+
+		// The escapes have already been checked.
+		key, _ := Unescape(rawKey)
+
+		for _, name := range SingleValuedParams {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+
+			for _, prev := range seen {
+				if prev == name {
+					return offset, true
+				}
+			}
+
+			seen = append(seen, name)
+		}
+
+		offset += len(pair) + 1
+	}
+
+	return 0, false
+}
+
+// validate runs the optional checks against the parsed URI.
+func (conf parser) validate(sipURI URI) error {
+	if conf.noPassword && (sipURI.hadPass || sipURI.pass != "") {
+		return MalformedURIError{Cause: DeprecatedPassword}
+	}
+
+	if conf.strictHost && !validHostPort(sipURI.host) {
+		return MalformedURIError{Cause: MalformedHost}
+	}
+
+	if conf.transport && sipURI.proto == SIPS && insecureTransport(sipURI.Params().Get("transport")) {
+		return MalformedURIError{Cause: TransportSchemeMismatch}
+	}
+
+	if conf.phoneUser && strings.EqualFold(sipURI.Params().Get("user"), "phone") {
+		// §19.1.1 "the user field ... is a telephone-subscriber"
+		number, _, _ := strings.Cut(sipURI.user, ";")
+
+		if number == "" || !validTelNumber(number, number[0] == '+') {
+			return MalformedURIError{Cause: MalformedUser}
+		}
+	}
+
+	if conf.utf8 {
+		if err := validComponents(sipURI, utf8.ValidString, ErrInvalidUTF8); err != nil {
+			return err
+		}
+	}
+
+	if conf.controlChars {
+		if err := validComponents(sipURI, noControlChars, ErrControlCharacter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validComponents checks each decoded component with valid, returning a
+// [MalformedURIError] wrapping err with the cause of the first invalid one.
+func validComponents(sipURI URI, valid func(string) bool, err error) error {
+	if !valid(sipURI.user) || !valid(sipURI.pass) {
+		return MalformedURIError{Cause: MalformedUser, Err: err}
+	}
+
+	if !valid(sipURI.host) {
+		return MalformedURIError{Cause: MalformedHost, Err: err}
+	}
+
+	if !validStore(sipURI.Params(), valid) {
+		return MalformedURIError{Cause: MalformedParams, Err: err}
+	}
+
+	if !validStore(sipURI.Headers(), valid) {
+		return MalformedURIError{Cause: MalformedHeaders, Err: err}
+	}
+
+	return nil
+}
+
+// validStore checks every key and value of the store with valid.
+func validStore(store KeyValueStore, valid func(string) bool) bool {
+	ok := true
+
+	store.ForEach(func(key, value string) bool {
+		ok = valid(key) && valid(value)
+
+		return ok
+	})
+
+	return ok
+}
+
+// noControlChars checks the input has no control characters or spaces, bytes
+// which are escaped in every component.
+func noControlChars(input string) bool {
+	for i := 0; i < len(input); i++ {
+		if c := input[i]; c <= ' ' || c == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validHostPort checks the host follows the host production of §25.1 with an
+// optional port.
+func validHostPort(hostport string) bool {
+	if strings.HasPrefix(hostport, "[") {
+		end := strings.IndexByte(hostport, ']')
+		if end < 0 {
+			return false
+		}
+
+		host, rest := hostport[1:end], hostport[end+1:]
+
+		if rest != "" && (rest[0] != ':' || !validPort(rest[1:])) {
+			return false
+		}
+
+		// RFC 6874 §2 IPv6addrz = IPv6address "%25" ZoneID
+		host, zone, hasZone := strings.Cut(host, "%")
+		if hasZone && zone == "" {
+			return false
+		}
+
+		return strings.Contains(host, ":") && net.ParseIP(host) != nil
+	}
+
+	host, port, hasPort := strings.Cut(hostport, ":")
+	if hasPort && !validPort(port) {
+		return false
+	}
+
+	if isDottedQuad(host) {
+		return net.ParseIP(host) != nil
+	}
+
+	return validHostname(host)
+}
+
+// isDottedQuad reports if the host looks like an IPv4 address, being four
+// labels of only digits. Fewer labels, such as 1.2.3, are left as hostnames.
+func isDottedQuad(host string) bool {
+	const octets = 4
+
+	labels := strings.Split(host, ".")
+	if len(labels) != octets {
+		return false
+	}
+
+	for _, label := range labels {
+		if label == "" {
+			return false
+		}
+
+		for i := 0; i < len(label); i++ {
+			if label[i] < '0' || label[i] > '9' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// validHostname checks each label is made up of alphanumeric characters and
+// '-', neither starting nor ending with a '-'. Unlike §25.1 the top label
+// may start with a digit, as relaxed by RFC 1123, so IPv4 addresses are
+// accepted.
+func validHostname(host string) bool {
+	const maxLabelLength = 63
+
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return false
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > maxLabelLength || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// validPort checks the port is a number in the range 0-65535.
+func validPort(port string) bool {
+	if port == "" || port[0] == '+' {
+		return false
+	}
+
+	_, err := strconv.ParseUint(port, 10, 16)
+
+	return err == nil
+}
@@ -0,0 +1,363 @@
+package sipuri_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/percivalalb/sipuri"
+)
+
+func TestPhoneUserValidation(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		valid bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:+1-212-555-1212:1234@gateway.com;user=phone", true, "RFC example"},
+		{"sip:+1-212-555-1234@gw.com;user=phone", true, "global number"},
+		{"sip:+44.(20).7946.0018@gw.com;user=phone", true, "visual separators"},
+		{"sip:863-1234;phone-context=+1-914-555@gw.com;user=phone", true, "local number with context"},
+		{"sip:*69%23@gw.com;user=phone", true, "dtmf digits"},
+		{"sip:alice@atlanta.com", true, "not a phone user"},
+		{"sip:alice@atlanta.com;user=ip", true, "ip user"},
+
+		{"sip:not%20a%20phone@gw.com;user=phone", false, "words"},
+		{"sip:+@gw.com;user=phone", false, "no digits"},
+		{"sip:+1-800-FLOWERS@gw.com;user=phone", false, "letters in global number"},
+		{"sip:;isub=1411@gw.com;user=phone", false, "empty number"},
+		{"sip:gw.com;user=phone", false, "no user"},
+		{"sip:+1-800-FLOWERS@gw.com;user=Phone", false, "mixed case phone user"},
+	}
+
+	for _, test := range tests {
+		for _, lazy := range []bool{false, true} {
+			parse := sipuri.ParseWithOptions
+			if lazy {
+				parse = lazily(parse, sipuri.WithLazy())
+			}
+
+			_, err := parse(test.uri, sipuri.WithPhoneUserValidation())
+
+			if test.valid && err != nil {
+				t.Fatalf("unexpected error %q in %s", err, test.msg)
+			}
+
+			if !test.valid && !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedUser}) {
+				t.Fatalf("expected malformed user error but got %q in %s", err, test.msg)
+			}
+
+			// Without the option all are accepted.
+			if _, err := parse(test.uri); err != nil {
+				t.Fatalf("unexpected error %q without validation in %s", err, test.msg)
+			}
+		}
+	}
+}
+
+func TestStrictHost(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		valid bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", true, "hostname"},
+		{"sip:alice@atlanta.com.", true, "fully qualified hostname"},
+		{"sip:alice@atlanta.com:5060", true, "hostname with port"},
+		{"sip:alice@a-1.example", true, "hyphenated label"},
+		{"sip:alice@localhost", true, "single label"},
+		{"sip:alice@192.0.2.4", true, "ipv4"},
+		{"sip:alice@192.0.2.4:5060", true, "ipv4 with port"},
+		{"sip:alice@1.2.3", true, "three numeric labels are a hostname"},
+		{"sip:alice@1.2.3.4.5", true, "five numeric labels are a hostname"},
+		{"sip:alice@[2001:db8::2:1]", true, "ipv6"},
+		{"sip:alice@[2001:db8::1]:5060", true, "ipv6 with port"},
+		{"sip:alice@" + strings.Repeat("a", 63) + ".com", true, "63 character label"},
+
+		{"sip:alice@..", false, "empty labels"},
+		{"sip:alice@atlanta..com", false, "empty label"},
+		{"sip:alice@atlanta.com..", false, "double trailing dot"},
+		{"sip:alice@exam%20ple.com", false, "space"},
+		{"sip:alice@exam_ple.com", false, "underscore"},
+		{"sip:alice@-atlanta.com", false, "leading hyphen"},
+		{"sip:alice@atlanta-.com", false, "trailing hyphen"},
+		{"sip:alice@" + strings.Repeat("a", 64) + ".com", false, "64 character label"},
+		{"sip:alice@atlanta.com:port", false, "non-numeric port"},
+		{"sip:alice@atlanta.com:65536", false, "port out of range"},
+		{"sip:alice@atlanta.com:", false, "empty port"},
+		{"sip:alice@[atlanta.com]", false, "bracketed hostname"},
+		{"sip:alice@[192.0.2.4]", false, "bracketed ipv4"},
+		{"sip:alice@999.1.1.1", false, "ipv4 octet out of range"},
+		{"sip:alice@999.999.999.999", false, "ipv4 octets out of range"},
+		{"sip:alice@192.0.2.256:5060", false, "ipv4 octet out of range with port"},
+		{"sip:alice@192.0.2.04", false, "ipv4 octet with leading zero"},
+	}
+
+	for _, test := range tests {
+		_, err := sipuri.ParseWithOptions(test.uri, sipuri.WithStrictHost())
+
+		if test.valid && err != nil {
+			t.Fatalf("unexpected error %q in %s", err, test.msg)
+		}
+
+		if !test.valid && !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedHost}) {
+			t.Fatalf("expected malformed host error but got %q in %s", err, test.msg)
+		}
+
+		_, strictErr := sipuri.ParseStrict(test.uri)
+		equalF(t, err, strictErr, "parse strict applies strict host in %s", test.msg)
+	}
+}
+
+func TestUTF8Validation(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		cause sipuri.MalformCause
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", sipuri.Unspecified, "ascii"},
+		{"sip:%C3%A9mile@atlanta.com;x=%E2%82%AC?subject=%F0%9F%93%9E", sipuri.Unspecified, "multi-byte characters"},
+
+		{"sip:%FF%FE@host", sipuri.MalformedUser, "invalid user"},
+		{"sip:alice:%C0%AF@host", sipuri.MalformedUser, "overlong password"},
+		{"sip:alice@ho%FFst", sipuri.MalformedHost, "invalid host"},
+		{"sip:alice@host;x=%ED%A0%80", sipuri.MalformedParams, "surrogate param value"},
+		{"sip:alice@host;%FF", sipuri.MalformedParams, "invalid flag param"},
+		{"sip:alice@host?subject=%E2%82", sipuri.MalformedHeaders, "truncated header value"},
+	}
+
+	for _, test := range tests {
+		for _, lazy := range []bool{false, true} {
+			parse := sipuri.ParseWithOptions
+			if lazy {
+				parse = lazily(parse, sipuri.WithLazy())
+			}
+
+			_, err := parse(test.uri, sipuri.WithUTF8Validation())
+
+			if test.cause == sipuri.Unspecified && err != nil {
+				t.Fatalf("unexpected error %q in %s", err, test.msg)
+			}
+
+			if test.cause != sipuri.Unspecified {
+				if !errors.Is(err, sipuri.MalformedURIError{Cause: test.cause}) || !errors.Is(err, sipuri.ErrInvalidUTF8) {
+					t.Fatalf("expected invalid UTF-8 %s error but got %q in %s", test.cause, err, test.msg)
+				}
+			}
+
+			if _, err := parse(test.uri); err != nil {
+				t.Fatalf("unexpected error %q without validation in %s", err, test.msg)
+			}
+		}
+	}
+}
+
+func TestControlCharValidation(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		cause sipuri.MalformCause
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com;transport=tcp?subject=project", sipuri.Unspecified, "no control characters"},
+		{"sip:%C3%A9mile@atlanta.com", sipuri.Unspecified, "multi-byte characters"},
+
+		{"sip:user@host\r\nEvil: header", sipuri.MalformedHost, "raw crlf in host"},
+		{"sip:user@host%0D%0AEvil:%20header", sipuri.MalformedHost, "escaped crlf in host"},
+		{"sip:al%09ice@atlanta.com", sipuri.MalformedUser, "tab in user"},
+		{"sip:alice:se%20cret@atlanta.com", sipuri.MalformedUser, "space in password"},
+		{"sip:alice@atlanta.com;x=a%7Fb", sipuri.MalformedParams, "delete in param"},
+		{"sip:alice@atlanta.com?subject=project%20x", sipuri.MalformedHeaders, "space in header"},
+	}
+
+	for _, test := range tests {
+		for _, lazy := range []bool{false, true} {
+			parse := sipuri.ParseWithOptions
+			if lazy {
+				parse = lazily(parse, sipuri.WithLazy())
+			}
+
+			_, err := parse(test.uri, sipuri.WithControlCharValidation())
+
+			if test.cause == sipuri.Unspecified && err != nil {
+				t.Fatalf("unexpected error %q in %s", err, test.msg)
+			}
+
+			if test.cause != sipuri.Unspecified {
+				if !errors.Is(err, sipuri.MalformedURIError{Cause: test.cause}) || !errors.Is(err, sipuri.ErrControlCharacter) {
+					t.Fatalf("expected control character %s error but got %q in %s", test.cause, err, test.msg)
+				}
+			}
+
+			if _, err := parse(test.uri); err != nil {
+				t.Fatalf("unexpected error %q without validation in %s", err, test.msg)
+			}
+		}
+	}
+}
+
+func TestWithoutPassword(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		valid bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:alice@atlanta.com", true, "no password"},
+		{"sip:atlanta.com;transport=tcp", true, "no user"},
+		{"sip:alice;x=%3A@atlanta.com", true, "escaped colon in user"},
+
+		{"sip:alice:secret@atlanta.com", false, "password"},
+		{"sip:alice:@atlanta.com", false, "empty password"},
+		{"sips:+1-212-555-1212:1234@gateway.com;user=phone", false, "password with phone user"},
+	}
+
+	for _, test := range tests {
+		for _, lazy := range []bool{false, true} {
+			parse := sipuri.ParseWithOptions
+			if lazy {
+				parse = lazily(parse, sipuri.WithLazy())
+			}
+
+			_, err := parse(test.uri, sipuri.WithoutPassword())
+
+			if test.valid && err != nil {
+				t.Fatalf("unexpected error %q in %s", err, test.msg)
+			}
+
+			if !test.valid && !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.DeprecatedPassword}) {
+				t.Fatalf("expected deprecated password error but got %q in %s", err, test.msg)
+			}
+
+			if _, err := sipuri.ParseStrict(test.uri); err != nil {
+				t.Fatalf("unexpected error %q from parse strict in %s", err, test.msg)
+			}
+		}
+	}
+
+	_, err := sipuri.ParseWithOptions("sip:alice:secret@atlanta.com", sipuri.WithoutPassword())
+	equalF(t, "sip: malformed uri: deprecated password", err.Error(), "error string")
+}
+
+func TestTransportValidation(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		valid bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sips:h;transport=tls", true, "tls"},
+		{"sips:h;transport=tcp", true, "tls over tcp"},
+		{"sips:h;transport=wss", true, "secure websocket"},
+		{"sips:h", true, "no transport"},
+		{"sip:h;transport=udp", true, "sip with udp"},
+		{"sip:h;transport=ws", true, "sip with websocket"},
+
+		{"sips:h;transport=udp", false, "udp"},
+		{"sips:h;transport=UDP", false, "upper case udp"},
+		{"sips:h;transport=sctp", false, "sctp"},
+		{"sips:h;transport=ws", false, "websocket"},
+	}
+
+	for _, test := range tests {
+		for _, lazy := range []bool{false, true} {
+			parse := sipuri.ParseWithOptions
+			if lazy {
+				parse = lazily(parse, sipuri.WithLazy())
+			}
+
+			_, err := parse(test.uri, sipuri.WithTransportValidation())
+
+			if test.valid && err != nil {
+				t.Fatalf("unexpected error %q in %s", err, test.msg)
+			}
+
+			if !test.valid && !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.TransportSchemeMismatch}) {
+				t.Fatalf("expected transport scheme mismatch error but got %q in %s", err, test.msg)
+			}
+
+			_, strictErr := sipuri.ParseStrict(test.uri)
+			equalF(t, err, strictErr, "parse strict applies transport validation in %s", test.msg)
+
+			if _, err := parse(test.uri); err != nil {
+				t.Fatalf("unexpected error %q from lenient parse in %s", err, test.msg)
+			}
+		}
+	}
+
+	_, err := sipuri.ParseWithOptions("sips:alice@atlanta.com;transport=udp", sipuri.WithTransportValidation())
+	equalF(t, "sip: malformed uri: transport scheme mismatch", err.Error(), "error string")
+}
+
+func TestSingleValuedParams(t *testing.T) {
+	t.Parallel()
+
+	type test struct {
+		uri   string
+		valid bool
+		msg   string
+	}
+
+	tests := []test{
+		{"sip:atlanta.com;transport=tcp;lr", true, "single values"},
+		{"sip:atlanta.com;x=1;x=2;lr", true, "other param repeated"},
+		{"sip:atlanta.com?transport=tcp&transport=udp", true, "headers ignored"},
+
+		{"sip:atlanta.com;transport=tcp;transport=udp", false, "conflicting transports"},
+		{"sip:atlanta.com;transport=tcp;transport=tcp", false, "same transport"},
+		{"sip:atlanta.com;lr;x=1;lr", false, "repeated flag"},
+		{"sip:atlanta.com;ttl=1;TTL=2", false, "different case"},
+		{"sip:atlanta.com;user=phone;%75ser=ip", false, "escaped name"},
+	}
+
+	for _, test := range tests {
+		for _, lazy := range []bool{false, true} {
+			parse := sipuri.ParseWithOptions
+			if lazy {
+				parse = lazily(parse, sipuri.WithLazy())
+			}
+
+			_, err := parse(test.uri, sipuri.WithSingleValuedParams())
+
+			if test.valid && err != nil {
+				t.Fatalf("unexpected error %q in %s", err, test.msg)
+			}
+
+			if !test.valid && !errors.Is(err, sipuri.MalformedURIError{Cause: sipuri.MalformedParams}) {
+				t.Fatalf("expected malformed params error but got %q in %s", err, test.msg)
+			}
+
+			if _, err := parse(test.uri); err != nil {
+				t.Fatalf("unexpected error %q without validation in %s", err, test.msg)
+			}
+		}
+
+		if _, err := sipuri.ParseStrict(test.uri); (err == nil) != test.valid {
+			t.Fatalf("unexpected error %v from parse strict in %s", err, test.msg)
+		}
+	}
+
+	_, err := sipuri.ParseWithOptions("sip:atlanta.com;transport=tcp;transport=udp", sipuri.WithSingleValuedParams())
+	equalF(t, "sip: malformed uri: malformed params", err.Error(), "error string")
+}